@@ -0,0 +1,71 @@
+package main
+
+import (
+    "flag"
+    "fmt"
+    "os"
+    "time"
+
+    "ntp/ntp"
+)
+
+// Nagios plugin exit codes.
+const (
+    stateOK       = 0
+    stateWarning  = 1
+    stateCritical = 2
+    stateUnknown  = 3
+)
+
+var stateNames = [...]string{"OK", "WARNING", "CRITICAL", "UNKNOWN"}
+
+func runCheck(args []string) {
+    var warn, crit time.Duration
+
+    fs := flag.NewFlagSet("check", flag.ContinueOnError)
+    fs.DurationVar(&warn, "warn", 100*time.Millisecond, "offset that raises WARNING")
+    fs.DurationVar(&crit, "crit", 500*time.Millisecond, "offset that raises CRITICAL")
+    client := newClientFlags(fs, "server", ntp.DefaultServer, "NTP server address, comma-separated for several")
+    if err := fs.Parse(args); err != nil {
+        os.Exit(stateUnknown)
+    }
+
+    opts, err := client.options()
+    if err != nil {
+        fmt.Printf("NTP UNKNOWN: %v\n", err)
+        os.Exit(stateUnknown)
+    }
+
+    m, err := measure(opts)
+    status, state := checkOffset(m, err, warn, crit)
+
+    fmt.Println(status)
+    os.Exit(state)
+}
+
+// checkOffset returns the plugin output line and exit code for a measurement.
+// The absolute offset is compared with the thresholds; any query error is UNKNOWN.
+func checkOffset(m *measurement, err error, warn, crit time.Duration) (string, int) {
+    if err != nil {
+        return fmt.Sprintf("NTP UNKNOWN: %v", err), stateUnknown
+    }
+
+    offset := m.Offset
+    if offset < 0 {
+        offset = -offset
+    }
+
+    state := stateOK
+    switch {
+    case offset >= crit:
+        state = stateCritical
+    case offset >= warn:
+        state = stateWarning
+    }
+
+    status := fmt.Sprintf("NTP %s: offset %.6f s from %s|offset=%.6fs;%.6f;%.6f;; delay=%.6fs;;;0; stratum=%d;;;0;16",
+        stateNames[state], m.Offset.Seconds(), m.Server,
+        m.Offset.Seconds(), warn.Seconds(), crit.Seconds(), m.Delay.Seconds(), m.Stratum)
+
+    return status, state
+}
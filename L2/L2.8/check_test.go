@@ -0,0 +1,62 @@
+package main
+
+import (
+    "errors"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestCheckOffset(t *testing.T) {
+    warn, crit := 100*time.Millisecond, 500*time.Millisecond
+
+    tests := []struct {
+        name     string
+        offset   time.Duration
+        err      error
+        state    int
+        expected string
+    }{
+        {
+            name:     "ok",
+            offset:   20 * time.Millisecond,
+            state:    stateOK,
+            expected: "NTP OK: offset 0.020000 s from pool.ntp.org|offset=0.020000s;0.100000;0.500000;; delay=0.010000s;;;0; stratum=2;;;0;16",
+        },
+        {
+            name:     "warning on negative offset",
+            offset:   -200 * time.Millisecond,
+            state:    stateWarning,
+            expected: "NTP WARNING: offset -0.200000 s",
+        },
+        {
+            name:     "critical",
+            offset:   time.Second,
+            state:    stateCritical,
+            expected: "NTP CRITICAL: offset 1.000000 s",
+        },
+        {
+            name:     "unknown on error",
+            err:      errors.New("timeout"),
+            state:    stateUnknown,
+            expected: "NTP UNKNOWN: timeout",
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            m := &measurement{Offset: tt.offset, Delay: 10 * time.Millisecond, Stratum: 2, Server: "pool.ntp.org"}
+            if tt.err != nil {
+                m = nil
+            }
+
+            status, state := checkOffset(m, tt.err, warn, crit)
+            if state != tt.state {
+                t.Errorf("checkOffset() state = %d, want %d", state, tt.state)
+            }
+            if !strings.HasPrefix(status, tt.expected) {
+                t.Errorf("checkOffset() = %q, want prefix %q", status, tt.expected)
+            }
+        })
+    }
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+    "context"
+    "flag"
+    "fmt"
+    "io"
+    "os"
+    "text/tabwriter"
+
+    "ntp/ntp"
+)
+
+func runCompare(args []string) {
+    fs := flag.NewFlagSet("compare", flag.ExitOnError)
+    client := newClientFlags(fs, "", "", "")
+    fs.Parse(args)
+
+    opts, err := client.options()
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+        os.Exit(1)
+    }
+
+    hosts := fs.Args()
+    if len(hosts) == 0 {
+        hosts = []string{ntp.DefaultServer}
+    }
+
+    comparisons := ntp.CompareServers(context.Background(), hosts, opts)
+    if err := writeComparisons(os.Stdout, comparisons); err != nil {
+        fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+        os.Exit(1)
+    }
+}
+
+func writeComparisons(w io.Writer, comparisons []ntp.Comparison) error {
+    tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+    fmt.Fprintln(tw, "ADDRESS\tSTRATUM\tREFID\tOFFSET\tDELAY\tLEAP\tSTATUS")
+
+    for _, c := range comparisons {
+        status := "ok"
+        if c.Err != nil {
+            status = c.Err.Error()
+        }
+
+        r := c.Response
+        if r == nil {
+            fmt.Fprintf(tw, "%s\t-\t-\t-\t-\t-\t%s\n", c.Address, status)
+            continue
+        }
+        fmt.Fprintf(tw, "%s\t%d\t%s\t%v\t%v\t%s\t%s\n",
+            c.Address, r.Stratum, r.ReferenceString(), r.ClockOffset, r.RTT, r.Leap, status)
+    }
+
+    return tw.Flush()
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+    "encoding/json"
+    "fmt"
+    "strconv"
+    "time"
+)
+
+// measurement is the outcome of a query as rendered by the output formats.
+// Estimated is set when the time was derived from the state file.
+type measurement struct {
+    Time        time.Time
+    Offset      time.Duration
+    Delay       time.Duration
+    Uncertainty time.Duration
+    Estimated   bool
+    Stratum     uint8
+    Server      string
+}
+
+type jsonMeasurement struct {
+    Time        string  `json:"time"`
+    Offset      float64 `json:"offset"`
+    Delay       float64 `json:"delay"`
+    Uncertainty float64 `json:"uncertainty"`
+    Estimated   bool    `json:"estimated,omitempty"`
+    Stratum     uint8   `json:"stratum"`
+    Server      string  `json:"server"`
+}
+
+// formatMeasurement renders m in the named format. Any unknown format is
+// used as a Go time layout. Offset and delay in JSON are in seconds.
+func formatMeasurement(m *measurement, format string, loc *time.Location) (string, error) {
+    t := m.Time.In(loc)
+
+    switch format {
+    case "text":
+        if m.Estimated {
+            return fmt.Sprintf("Estimated time (±%v): %s", m.Uncertainty, t), nil
+        }
+        return fmt.Sprintf("Current time by NTP: %s", t), nil
+    case "rfc3339nano":
+        return t.Format(time.RFC3339Nano), nil
+    case "unix":
+        return strconv.FormatInt(t.Unix(), 10), nil
+    case "unixmilli":
+        return strconv.FormatInt(t.UnixMilli(), 10), nil
+    case "json":
+        data, err := json.Marshal(jsonMeasurement{
+            Time:        t.Format(time.RFC3339Nano),
+            Offset:      m.Offset.Seconds(),
+            Delay:       m.Delay.Seconds(),
+            Uncertainty: m.Uncertainty.Seconds(),
+            Estimated:   m.Estimated,
+            Stratum:     m.Stratum,
+            Server:      m.Server,
+        })
+        if err != nil {
+            return "", err
+        }
+        return string(data), nil
+    case "":
+        return "", fmt.Errorf("empty output format")
+    default:
+        return t.Format(format), nil
+    }
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+    "testing"
+    "time"
+)
+
+func TestFormatMeasurement(t *testing.T) {
+    m := &measurement{
+        Time:        time.Date(2026, 10, 17, 12, 30, 45, 123456789, time.UTC),
+        Offset:      1500 * time.Millisecond,
+        Delay:       20 * time.Millisecond,
+        Uncertainty: 15 * time.Millisecond,
+        Stratum:     2,
+        Server:      "pool.ntp.org",
+    }
+    moscow := time.FixedZone("MSK", 3*60*60)
+
+    tests := []struct {
+        name      string
+        format    string
+        loc       *time.Location
+        estimated bool
+        expected  string
+    }{
+        {
+            name:     "text",
+            format:   "text",
+            loc:      time.UTC,
+            expected: "Current time by NTP: 2026-10-17 12:30:45.123456789 +0000 UTC",
+        },
+        {
+            name:     "rfc3339nano in zone",
+            format:   "rfc3339nano",
+            loc:      moscow,
+            expected: "2026-10-17T15:30:45.123456789+03:00",
+        },
+        {
+            name:     "unix seconds",
+            format:   "unix",
+            loc:      time.UTC,
+            expected: "1792240245",
+        },
+        {
+            name:     "unix millis",
+            format:   "unixmilli",
+            loc:      time.UTC,
+            expected: "1792240245123",
+        },
+        {
+            name:     "json",
+            format:   "json",
+            loc:      time.UTC,
+            expected: `{"time":"2026-10-17T12:30:45.123456789Z","offset":1.5,"delay":0.02,"uncertainty":0.015,"stratum":2,"server":"pool.ntp.org"}`,
+        },
+        {
+            name:      "estimated text",
+            format:    "text",
+            loc:       time.UTC,
+            estimated: true,
+            expected:  "Estimated time (±15ms): 2026-10-17 12:30:45.123456789 +0000 UTC",
+        },
+        {
+            name:      "estimated json",
+            format:    "json",
+            loc:       time.UTC,
+            estimated: true,
+            expected:  `{"time":"2026-10-17T12:30:45.123456789Z","offset":1.5,"delay":0.02,"uncertainty":0.015,"estimated":true,"stratum":2,"server":"pool.ntp.org"}`,
+        },
+        {
+            name:     "custom layout",
+            format:   "02.01.2006 15:04",
+            loc:      moscow,
+            expected: "17.10.2026 15:30",
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            m := m
+            if tt.estimated {
+                estimated := *m
+                estimated.Estimated = true
+                m = &estimated
+            }
+
+            result, err := formatMeasurement(m, tt.format, tt.loc)
+            if err != nil {
+                t.Fatalf("formatMeasurement() unexpected error: %v", err)
+            }
+            if result != tt.expected {
+                t.Errorf("formatMeasurement() = %q, want %q", result, tt.expected)
+            }
+        })
+    }
+}
@@ -1,17 +1,219 @@
 package main
 
 import (
+    "flag"
     "fmt"
     "os"
+    "strings"
+    "time"
+
     "ntp/ntp"
 )
 
 func main() {
-    time, err := ntp.GetCurrentTime()
+    mode, args := "query", os.Args[1:]
+    if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+        mode, args = args[0], args[1:]
+    }
+
+    switch mode {
+    case "query":
+        runQuery(args)
+    case "serve":
+        runServe(args)
+    case "monitor":
+        runMonitor(args)
+    case "compare":
+        runCompare(args)
+    case "check":
+        runCheck(args)
+    default:
+        fmt.Fprintf(os.Stderr, "Error: unknown mode %q (want query, serve, monitor, compare or check)\n", mode)
+        os.Exit(2)
+    }
+}
+
+func runQuery(args []string) {
+    var (
+        format    string
+        tz        string
+        stateFile string
+        fallback  bool
+    )
+
+    fs := flag.NewFlagSet("query", flag.ExitOnError)
+    fs.StringVar(&format, "format", "text", "output format: text, rfc3339nano, unix, unixmilli, json or a Go time layout")
+    fs.StringVar(&tz, "tz", "", "time zone to render the time in, e.g. UTC or Europe/Moscow")
+    fs.StringVar(&stateFile, "state", "", "file keeping the last good offset and drift rate")
+    fs.BoolVar(&fallback, "fallback", false, "estimate the time from -state when the server cannot be reached")
+    client := newClientFlags(fs, "server", ntp.DefaultServer, "NTP server address, comma-separated for several")
+    fs.Parse(args)
+
+    opts, err := client.options()
     if err != nil {
         fmt.Fprintf(os.Stderr, "Error: %v\n", err)
         os.Exit(1)
     }
-    
-    fmt.Printf("Current time by NTP: %s\n", time)
-}
\ No newline at end of file
+    if fallback && stateFile == "" {
+        fmt.Fprintln(os.Stderr, "Error: -fallback requires -state")
+        os.Exit(1)
+    }
+
+    loc := time.Local
+    if tz != "" {
+        loc, err = time.LoadLocation(tz)
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+            os.Exit(1)
+        }
+    }
+
+    m, err := measure(opts)
+    if stateFile != "" {
+        m, err = withState(stateFile, m, err, fallback)
+    }
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+        os.Exit(1)
+    }
+
+    output, err := formatMeasurement(m, format, loc)
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+        os.Exit(1)
+    }
+
+    fmt.Println(output)
+}
+
+// clientFlags holds the NTP client flags shared by the modes.
+type clientFlags struct {
+    opts     ntp.Options
+    keysFile string
+    keyID    uint
+    leapFile string
+}
+
+// newClientFlags registers the NTP client flags, the server address under
+// serverFlag unless it is empty.
+func newClientFlags(fs *flag.FlagSet, serverFlag, server, usage string) *clientFlags {
+    f := &clientFlags{}
+
+    if serverFlag != "" {
+        fs.StringVar(&f.opts.Server, serverFlag, server, usage)
+    }
+    fs.DurationVar(&f.opts.Timeout, "timeout", 5*time.Second, "query timeout")
+    fs.IntVar(&f.opts.Version, "version", 4, "NTP protocol version")
+    fs.IntVar(&f.opts.LocalPort, "local-port", 0, "local UDP port (0 for any)")
+    fs.IntVar(&f.opts.TTL, "ttl", 0, "IP time-to-live of the query (0 for system default)")
+    fs.DurationVar(&f.opts.MaxRootDistance, "max-distance", 1500*time.Millisecond, "maximum accepted root distance")
+    fs.DurationVar(&f.opts.MaxRTT, "max-rtt", time.Second, "maximum accepted round-trip time")
+    fs.StringVar(&f.keysFile, "keys", "", "ntp.keys file with symmetric keys")
+    fs.UintVar(&f.keyID, "key-id", 0, "ID of the key from -keys used to authenticate queries")
+    fs.StringVar(&f.leapFile, "leap-seconds", "", "leap-seconds.list file (default: use the leap indicator)")
+    fs.DurationVar(&f.opts.SmearWindow, "smear", 0, "smear leap seconds over this window (0 to step)")
+
+    return f
+}
+
+// options returns the parsed options, loading the leap seconds and the
+// authentication key if requested.
+func (f *clientFlags) options() (*ntp.Options, error) {
+    opts := f.opts
+    if f.leapFile != "" {
+        table, err := ntp.LoadLeapSeconds(f.leapFile)
+        if err != nil {
+            return nil, err
+        }
+        opts.LeapSeconds = table
+    }
+    if f.keyID == 0 {
+        return &opts, nil
+    }
+    if f.keysFile == "" {
+        return nil, fmt.Errorf("-key-id requires -keys")
+    }
+
+    keys, err := ntp.LoadKeys(f.keysFile)
+    if err != nil {
+        return nil, err
+    }
+    key, ok := keys[uint32(f.keyID)]
+    if !ok {
+        return nil, fmt.Errorf("key %d not found in %s", f.keyID, f.keysFile)
+    }
+    opts.Key = key
+
+    return &opts, nil
+}
+
+// measure queries one server, or several when opts.Server is a comma-separated list.
+// For several servers the figures of the truechimer with the smallest root
+// distance are reported together with the combined offset.
+func measure(opts *ntp.Options) (*measurement, error) {
+    servers := strings.Split(opts.Server, ",")
+    if len(servers) == 1 {
+        response, err := ntp.Query(opts.Server, opts)
+        if err != nil {
+            return nil, fmt.Errorf("failed to query NTP server: %w", err)
+        }
+        return &measurement{
+            Time:        ntp.ApplyLeap(time.Now().Add(response.ClockOffset), response.Leap, opts),
+            Offset:      response.ClockOffset,
+            Delay:       response.RTT,
+            Uncertainty: response.RootDistance,
+            Stratum:     response.Stratum,
+            Server:      opts.Server,
+        }, nil
+    }
+
+    result, err := ntp.GetCurrentTimeFrom(servers, opts)
+    if err != nil {
+        return nil, err
+    }
+
+    var best *ntp.ServerResult
+    for i := range result.Servers {
+        sr := &result.Servers[i]
+        if sr.Truechimer && (best == nil || sr.Distance < best.Distance) {
+            best = sr
+        }
+    }
+
+    return &measurement{
+        Time:        result.Time,
+        Offset:      result.Offset,
+        Delay:       best.RTT,
+        Uncertainty: best.Distance,
+        Stratum:     best.Stratum,
+        Server:      best.Server,
+    }, nil
+}
+
+// withState records a successful measurement in the state file. When the
+// server could not be reached and fallback is set, the time is estimated
+// from the file.
+func withState(path string, m *measurement, err error, fallback bool) (*measurement, error) {
+    if err != nil && !fallback {
+        return nil, err
+    }
+
+    var offset, distance time.Duration
+    if m != nil {
+        offset, distance = m.Offset, m.Uncertainty
+    }
+    e, err := ntp.RecordOrEstimate(path, offset, distance, err)
+    if err != nil {
+        return nil, err
+    }
+    if !e.Estimated {
+        return m, nil
+    }
+
+    return &measurement{
+        Time:        e.Time,
+        Offset:      e.Offset,
+        Uncertainty: e.Uncertainty,
+        Estimated:   true,
+    }, nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+    "flag"
+    "log"
+    "net/http"
+    "os"
+    "os/signal"
+    "syscall"
+    "time"
+
+    "ntp/ntp"
+)
+
+func runMonitor(args []string) {
+    var (
+        listen   string
+        interval time.Duration
+        history  int
+    )
+
+    fs := flag.NewFlagSet("monitor", flag.ExitOnError)
+    fs.StringVar(&listen, "listen", "127.0.0.1:9123", "HTTP address of the metrics endpoint")
+    fs.DurationVar(&interval, "interval", time.Minute, "polling interval")
+    fs.IntVar(&history, "history", 64, "number of samples kept")
+    client := newClientFlags(fs, "server", ntp.DefaultServer, "NTP server address")
+    fs.Parse(args)
+
+    opts, err := client.options()
+    if err != nil {
+        log.Fatalf("Error: %v", err)
+    }
+
+    monitor := &ntp.Monitor{
+        Options:     opts,
+        Interval:    interval,
+        HistorySize: history,
+    }
+
+    mux := http.NewServeMux()
+    mux.Handle("/metrics", monitor)
+    server := &http.Server{Addr: listen, Handler: mux}
+
+    done := make(chan struct{})
+    go func() {
+        signalCh := make(chan os.Signal, 1)
+        signal.Notify(signalCh, os.Interrupt, syscall.SIGTERM)
+        <-signalCh
+        close(done)
+        server.Close()
+    }()
+
+    go monitor.Run(done)
+
+    log.Printf("Monitoring %s, metrics on http://%s/metrics", opts.Server, listen)
+    if err := server.ListenAndServe(); err != http.ErrServerClosed {
+        log.Fatalf("Error: %v", err)
+    }
+}
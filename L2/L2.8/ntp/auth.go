@@ -0,0 +1,169 @@
+package ntp
+
+import (
+    "bufio"
+    "crypto/md5"
+    "crypto/sha1"
+    "crypto/sha256"
+    "crypto/subtle"
+    "encoding/binary"
+    "encoding/hex"
+    "fmt"
+    "hash"
+    "io"
+    "os"
+    "strconv"
+    "strings"
+)
+
+// maxDigestSize is the longest digest carried in an NTPv4 MAC; longer digests are truncated.
+const maxDigestSize = 20
+
+// Key is a symmetric key used to authenticate NTP packets.
+type Key struct {
+    ID     uint32
+    Type   string
+    Secret []byte
+}
+
+// Keys maps key IDs to keys.
+type Keys map[uint32]*Key
+
+// AuthError reports a reply or request that failed symmetric key authentication.
+type AuthError struct {
+    KeyID  uint32
+    Reason string
+}
+
+func (e *AuthError) Error() string {
+    return fmt.Sprintf("NTP authentication with key %d failed: %s", e.KeyID, e.Reason)
+}
+
+// LoadKeys reads keys from a file in the ntp.keys format.
+func LoadKeys(path string) (Keys, error) {
+    file, err := os.Open(path)
+    if err != nil {
+        return nil, err
+    }
+    defer file.Close()
+
+    return ParseKeys(file)
+}
+
+// ParseKeys parses keys in the ntp.keys format: one "id type key" per line,
+// where type is MD5, SHA1 or SHA256 and key is ASCII of up to 20 characters
+// or a hex string. Text after # is a comment.
+func ParseKeys(r io.Reader) (Keys, error) {
+    keys := make(Keys)
+
+    scanner := bufio.NewScanner(r)
+    lineNum := 0
+    for scanner.Scan() {
+        lineNum++
+        line := scanner.Text()
+        if i := strings.IndexByte(line, '#'); i >= 0 {
+            line = line[:i]
+        }
+        fields := strings.Fields(line)
+        if len(fields) == 0 {
+            continue
+        }
+        if len(fields) != 3 {
+            return nil, fmt.Errorf("keys line %d: want \"id type key\"", lineNum)
+        }
+
+        id, err := strconv.ParseUint(fields[0], 10, 32)
+        if err != nil || id == 0 {
+            return nil, fmt.Errorf("keys line %d: invalid key id %q", lineNum, fields[0])
+        }
+
+        keyType := strings.ToUpper(fields[1])
+        if newDigest(keyType) == nil {
+            return nil, fmt.Errorf("keys line %d: unsupported key type %q", lineNum, fields[1])
+        }
+
+        secret := []byte(fields[2])
+        if len(fields[2]) > maxDigestSize {
+            secret, err = hex.DecodeString(fields[2])
+            if err != nil {
+                return nil, fmt.Errorf("keys line %d: invalid hex key", lineNum)
+            }
+        }
+
+        keys[uint32(id)] = &Key{ID: uint32(id), Type: keyType, Secret: secret}
+    }
+
+    return keys, scanner.Err()
+}
+
+// newDigest returns the hash for keyType in any case, or nil if unsupported.
+func newDigest(keyType string) hash.Hash {
+    switch strings.ToUpper(keyType) {
+    case "MD5":
+        return md5.New()
+    case "SHA1":
+        return sha1.New()
+    case "SHA256":
+        return sha256.New()
+    default:
+        return nil
+    }
+}
+
+// check reports a key whose type has no digest, so that a Key built by
+// hand fails like a bad reply instead of panicking in digest.
+func (k *Key) check() error {
+    if newDigest(k.Type) == nil {
+        return &AuthError{KeyID: k.ID, Reason: fmt.Sprintf("unsupported key type %q", k.Type)}
+    }
+    return nil
+}
+
+// digest computes hash(secret || data) as ntpd does.
+func (k *Key) digest(data []byte) []byte {
+    h := newDigest(k.Type)
+    h.Write(k.Secret)
+    h.Write(data)
+
+    sum := h.Sum(nil)
+    if len(sum) > maxDigestSize {
+        sum = sum[:maxDigestSize]
+    }
+    return sum
+}
+
+// sign appends the key ID and the message digest to msg.
+func (k *Key) sign(msg []byte) []byte {
+    mac := binary.BigEndian.AppendUint32(nil, k.ID)
+    mac = append(mac, k.digest(msg)...)
+    return append(msg, mac...)
+}
+
+// macKeyID returns the key ID of the MAC following the packet header, if any.
+func macKeyID(msg []byte) (uint32, bool) {
+    if len(msg) < packetSize+4 {
+        return 0, false
+    }
+    return binary.BigEndian.Uint32(msg[packetSize:]), true
+}
+
+// verify checks the MAC following the packet header in msg.
+func (k *Key) verify(msg []byte) error {
+    if err := k.check(); err != nil {
+        return err
+    }
+    id, ok := macKeyID(msg)
+    if !ok {
+        return &AuthError{KeyID: k.ID, Reason: "no MAC"}
+    }
+    if id != k.ID {
+        return &AuthError{KeyID: k.ID, Reason: fmt.Sprintf("MAC uses key %d", id)}
+    }
+
+    want := k.digest(msg[:packetSize])
+    if got := msg[packetSize+4:]; subtle.ConstantTimeCompare(got, want) != 1 {
+        return &AuthError{KeyID: k.ID, Reason: "digest mismatch"}
+    }
+
+    return nil
+}
@@ -0,0 +1,112 @@
+package ntp
+
+import (
+    "bytes"
+    "errors"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestParseKeys(t *testing.T) {
+    input := `# ntp.keys
+1 MD5 secret
+2 sha1 0123456789abcdef0123456789abcdef01234567  # hex key
+
+3 SHA256 passphrase
+`
+
+    keys, err := ParseKeys(strings.NewReader(input))
+    if err != nil {
+        t.Fatalf("ParseKeys() unexpected error: %v", err)
+    }
+
+    if len(keys) != 3 {
+        t.Fatalf("ParseKeys() returned %d keys, want 3", len(keys))
+    }
+    if k := keys[1]; k.Type != "MD5" || string(k.Secret) != "secret" {
+        t.Errorf("key 1 = %+v", k)
+    }
+    if k := keys[2]; k.Type != "SHA1" || len(k.Secret) != 20 || k.Secret[0] != 0x01 {
+        t.Errorf("key 2 = %+v", k)
+    }
+
+    for _, bad := range []string{
+        "1 MD5",
+        "x MD5 secret",
+        "0 MD5 secret",
+        "1 DES secret",
+        "1 SHA1 not-a-hex-key-longer-than-twenty",
+    } {
+        if _, err := ParseKeys(strings.NewReader(bad)); err == nil {
+            t.Errorf("ParseKeys(%q) expected error, got none", bad)
+        }
+    }
+}
+
+func TestKeySignVerify(t *testing.T) {
+    key := &Key{ID: 7, Type: "SHA256", Secret: []byte("secret")}
+    msg := key.sign((&packet{Version: 4, Mode: modeClient}).marshal())
+
+    if len(msg) != packetSize+4+maxDigestSize {
+        t.Errorf("signed length = %d, want %d", len(msg), packetSize+4+maxDigestSize)
+    }
+    if err := key.verify(msg); err != nil {
+        t.Errorf("verify() unexpected error: %v", err)
+    }
+
+    tampered := bytes.Clone(msg)
+    tampered[1] = 1
+    var authErr *AuthError
+    if err := key.verify(tampered); !errors.As(err, &authErr) {
+        t.Errorf("verify() of tampered packet error = %v, want *AuthError", err)
+    }
+}
+
+func TestAuthenticatedQuery(t *testing.T) {
+    keys := Keys{1: {ID: 1, Type: "MD5", Secret: []byte("secret")}}
+    addr := startServer(t, &Server{Keys: keys})
+
+    if _, err := Query(addr, &Options{Timeout: time.Second, Key: keys[1]}); err != nil {
+        t.Errorf("Query() with valid key unexpected error: %v", err)
+    }
+
+    wrong := &Key{ID: 1, Type: "MD5", Secret: []byte("guess")}
+    if _, err := Query(addr, &Options{Timeout: 200 * time.Millisecond, Key: wrong}); err == nil {
+        t.Error("Query() with wrong key expected error, got none")
+    }
+
+    if _, err := Query(addr, &Options{Timeout: 200 * time.Millisecond}); err == nil {
+        t.Error("Query() without key expected error, got none")
+    }
+}
+
+func TestAuthenticatedQueryUnsignedReply(t *testing.T) {
+    addr := startServer(t, &Server{})
+    key := &Key{ID: 1, Type: "SHA1", Secret: []byte("secret")}
+
+    var authErr *AuthError
+    if _, err := Query(addr, &Options{Timeout: time.Second, Key: key}); !errors.As(err, &authErr) {
+        t.Errorf("Query() error = %v, want *AuthError", err)
+    }
+}
+
+func TestQueryKeyType(t *testing.T) {
+    keys := Keys{1: {ID: 1, Type: "sha1", Secret: []byte("secret")}}
+    addr := startServer(t, &Server{Keys: keys})
+
+    if _, err := Query(addr, &Options{Timeout: time.Second, Key: keys[1]}); err != nil {
+        t.Errorf("Query() with lower-case key type unexpected error: %v", err)
+    }
+
+    bad := &Key{ID: 1, Type: "SHA512", Secret: []byte("secret")}
+    var authErr *AuthError
+    if _, err := Query(addr, &Options{Timeout: time.Second, Key: bad}); !errors.As(err, &authErr) {
+        t.Errorf("Query() with unsupported key type error = %v, want *AuthError", err)
+    }
+
+    addr = startServer(t, &Server{Keys: Keys{1: bad}})
+    if _, err := Query(addr, &Options{Timeout: 200 * time.Millisecond, Key: keys[1]}); err == nil {
+        t.Error("Query() to server with unsupported key type expected error, got none")
+    }
+}
@@ -0,0 +1,192 @@
+package ntp
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "net"
+    "strconv"
+    "time"
+)
+
+const (
+    defaultPort    = 123
+    defaultTimeout = 5 * time.Second
+    defaultVersion = 4
+)
+
+// Response holds the server reply together with the values derived from it.
+type Response struct {
+    Time           time.Time
+    ClockOffset    time.Duration
+    RTT            time.Duration
+    Precision      time.Duration
+    Stratum        uint8
+    ReferenceID    uint32
+    ReferenceTime  time.Time
+    RootDelay      time.Duration
+    RootDispersion time.Duration
+    RootDistance   time.Duration
+    Leap           LeapIndicator
+    KissCode       string
+}
+
+// Query sends a single SNTP request to server and returns its reply.
+// The server address may omit the port, 123 is used then. When the reply
+// fails validation, the response is returned along with the typed error.
+func Query(server string, opts *Options) (*Response, error) {
+    return QueryContext(context.Background(), server, opts)
+}
+
+// QueryContext is like Query but bounds name resolution and the exchange by ctx.
+func QueryContext(ctx context.Context, server string, opts *Options) (*Response, error) {
+    if opts == nil {
+        opts = &Options{}
+    }
+    if opts.Key != nil {
+        if err := opts.Key.check(); err != nil {
+            return nil, err
+        }
+    }
+
+    // The timeout bounds name resolution as well as the exchange.
+    timeoutCtx, cancel := context.WithTimeout(ctx, queryTimeout(opts))
+    defer cancel()
+
+    conn, err := dial(timeoutCtx, server, opts)
+    if err != nil {
+        return nil, contextError(ctx, err)
+    }
+    defer conn.Close()
+
+    deadline, _ := timeoutCtx.Deadline()
+    if err := conn.SetDeadline(deadline); err != nil {
+        return nil, err
+    }
+
+    stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+    defer stop()
+
+    version := uint8(opts.Version)
+    if version == 0 {
+        version = defaultVersion
+    }
+
+    request := &packet{Version: version, Mode: modeClient}
+
+    sent := time.Now()
+    request.TransmitTime = toNtpTime(sent)
+    msg := request.marshal()
+    if opts.Key != nil {
+        msg = opts.Key.sign(msg)
+    }
+    if _, err := conn.Write(msg); err != nil {
+        return nil, contextError(ctx, err)
+    }
+
+    buf := make([]byte, 1024)
+    n, err := conn.Read(buf)
+    if err != nil {
+        return nil, contextError(ctx, err)
+    }
+    received := sent.Add(time.Since(sent))
+
+    var reply packet
+    if err := reply.unmarshal(buf[:n]); err != nil {
+        return nil, err
+    }
+    if reply.Mode != modeServer {
+        return nil, fmt.Errorf("unexpected NTP mode %d", reply.Mode)
+    }
+    if reply.OriginTime != request.TransmitTime {
+        return nil, errors.New("NTP reply does not match the request")
+    }
+    if reply.TransmitTime == 0 {
+        return nil, errors.New("NTP reply has no transmit time")
+    }
+    if opts.Key != nil {
+        if err := opts.Key.verify(buf[:n]); err != nil {
+            return nil, err
+        }
+    }
+
+    response := newResponse(&reply, sent, received)
+
+    return response, validate(response, opts)
+}
+
+func newResponse(p *packet, sent, received time.Time) *Response {
+    t1, t2, t3, t4 := sent, p.ReceiveTime.Time(), p.TransmitTime.Time(), received
+
+    r := &Response{
+        Time:           t3,
+        ClockOffset:    (t2.Sub(t1) + t3.Sub(t4)) / 2,
+        RTT:            t4.Sub(t1) - t3.Sub(t2),
+        Precision:      precisionToDuration(p.Precision),
+        Stratum:        p.Stratum,
+        ReferenceID:    p.ReferenceID,
+        ReferenceTime:  p.ReferenceTime.Time(),
+        RootDelay:      p.RootDelay.Duration(),
+        RootDispersion: p.RootDispersion.Duration(),
+        Leap:           p.Leap,
+    }
+    if r.RTT < 0 {
+        r.RTT = 0
+    }
+    r.RootDistance = (r.RTT+r.RootDelay)/2 + r.RootDispersion
+
+    if p.Stratum == 0 {
+        r.KissCode = refIDString(p.ReferenceID)
+    }
+
+    return r
+}
+
+func dial(ctx context.Context, server string, opts *Options) (net.Conn, error) {
+    if _, _, err := net.SplitHostPort(server); err != nil {
+        server = net.JoinHostPort(server, strconv.Itoa(defaultPort))
+    }
+
+    dialer := net.Dialer{Control: socketControl(opts.TTL)}
+    if opts.LocalPort != 0 {
+        dialer.LocalAddr = &net.UDPAddr{Port: opts.LocalPort}
+    }
+
+    return dialer.DialContext(ctx, "udp", server)
+}
+
+// queryTimeout returns opts.Timeout, or the default if unset.
+func queryTimeout(opts *Options) time.Duration {
+    if opts == nil || opts.Timeout <= 0 {
+        return defaultTimeout
+    }
+    return opts.Timeout
+}
+
+// concurrent reports whether queries with opts may run at the same time.
+// A fixed LocalPort can only be bound by one query at once.
+func concurrent(opts *Options) bool {
+    return opts == nil || opts.LocalPort == 0
+}
+
+// contextError prefers the context error over the timeout it caused. The
+// socket deadline may fire before the context notices its own, so a timeout
+// at or past the context deadline is reported as the context's.
+func contextError(ctx context.Context, err error) error {
+    if ctxErr := ctx.Err(); ctxErr != nil {
+        return ctxErr
+    }
+    var netErr net.Error
+    if d, ok := ctx.Deadline(); ok && !time.Now().Before(d) && errors.As(err, &netErr) && netErr.Timeout() {
+        return context.DeadlineExceeded
+    }
+    return err
+}
+
+func refIDString(id uint32) string {
+    b := []byte{byte(id >> 24), byte(id >> 16), byte(id >> 8), byte(id)}
+    for len(b) > 0 && b[len(b)-1] == 0 {
+        b = b[:len(b)-1]
+    }
+    return string(b)
+}
@@ -0,0 +1,323 @@
+package ntp
+
+import (
+    "context"
+    "errors"
+    "net"
+    "os"
+    "testing"
+    "time"
+)
+
+// startTestServer answers SNTP requests with a clock shifted by offset.
+// The reply can be altered by modify before it is sent.
+func startTestServer(t *testing.T, offset time.Duration, modify func(*packet)) string {
+    t.Helper()
+
+    conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatalf("listen: %v", err)
+    }
+    t.Cleanup(func() { conn.Close() })
+
+    go func() {
+        buf := make([]byte, 1024)
+        for {
+            n, addr, err := conn.ReadFrom(buf)
+            if err != nil {
+                return
+            }
+            received := time.Now().Add(offset)
+
+            var request packet
+            if err := request.unmarshal(buf[:n]); err != nil {
+                continue
+            }
+
+            reply := &packet{
+                Version:        request.Version,
+                Mode:           modeServer,
+                Stratum:        2,
+                Precision:      -20,
+                RootDelay:      toNtpShort(10 * time.Millisecond),
+                RootDispersion: toNtpShort(5 * time.Millisecond),
+                ReferenceID:    0x7f000001,
+                ReferenceTime:  toNtpTime(received.Add(-time.Minute)),
+                OriginTime:     request.TransmitTime,
+                ReceiveTime:    toNtpTime(received),
+            }
+            if modify != nil {
+                modify(reply)
+            }
+            reply.TransmitTime = toNtpTime(time.Now().Add(offset))
+
+            conn.WriteTo(reply.marshal(), addr)
+        }
+    }()
+
+    return conn.LocalAddr().String()
+}
+
+func TestPacketRoundTrip(t *testing.T) {
+    now := time.Now()
+    p := packet{
+        Leap:           LeapAddSecond,
+        Version:        4,
+        Mode:           modeServer,
+        Stratum:        3,
+        Poll:           6,
+        Precision:      -23,
+        RootDelay:      toNtpShort(1500 * time.Microsecond),
+        RootDispersion: toNtpShort(time.Second),
+        ReferenceID:    0x47505300,
+        ReferenceTime:  toNtpTime(now.Add(-time.Hour)),
+        OriginTime:     toNtpTime(now.Add(-time.Second)),
+        ReceiveTime:    toNtpTime(now),
+        TransmitTime:   toNtpTime(now.Add(time.Millisecond)),
+    }
+
+    var decoded packet
+    if err := decoded.unmarshal(p.marshal()); err != nil {
+        t.Fatalf("unmarshal: %v", err)
+    }
+    if decoded != p {
+        t.Errorf("decoded packet = %+v, want %+v", decoded, p)
+    }
+
+    if err := decoded.unmarshal(make([]byte, packetSize-1)); err != errShortPacket {
+        t.Errorf("unmarshal short packet error = %v, want %v", err, errShortPacket)
+    }
+}
+
+func TestNtpTimeConversion(t *testing.T) {
+    tests := []time.Time{
+        time.Date(1999, 12, 31, 23, 59, 59, 999999000, time.UTC),
+        time.Date(2026, 10, 17, 12, 0, 0, 500000000, time.UTC),
+        time.Date(2036, 2, 7, 6, 28, 17, 0, time.UTC),
+        time.Date(2050, 1, 1, 0, 0, 0, 123456000, time.UTC),
+    }
+
+    for _, want := range tests {
+        got := toNtpTime(want).Time()
+        if diff := got.Sub(want); diff < -time.Nanosecond || diff > time.Nanosecond {
+            t.Errorf("toNtpTime(%v).Time() = %v", want, got)
+        }
+    }
+}
+
+func TestQuery(t *testing.T) {
+    offset := 3 * time.Second
+    addr := startTestServer(t, offset, nil)
+
+    response, err := Query(addr, &Options{Timeout: time.Second})
+    if err != nil {
+        t.Fatalf("Query() unexpected error: %v", err)
+    }
+
+    if diff := response.ClockOffset - offset; diff < -50*time.Millisecond || diff > 50*time.Millisecond {
+        t.Errorf("ClockOffset = %v, want about %v", response.ClockOffset, offset)
+    }
+    if response.RTT < 0 || response.RTT > time.Second {
+        t.Errorf("RTT = %v, want between 0 and 1s", response.RTT)
+    }
+    if response.Stratum != 2 {
+        t.Errorf("Stratum = %d, want 2", response.Stratum)
+    }
+    if diff := response.RootDelay - 10*time.Millisecond; diff < -100*time.Microsecond || diff > 100*time.Microsecond {
+        t.Errorf("RootDelay = %v, want about 10ms", response.RootDelay)
+    }
+}
+
+func TestQueryMismatchedOrigin(t *testing.T) {
+    addr := startTestServer(t, 0, func(p *packet) { p.OriginTime++ })
+
+    if _, err := Query(addr, &Options{Timeout: time.Second}); err == nil {
+        t.Error("Query() expected error for mismatched origin, got none")
+    }
+}
+
+func TestQueryValidation(t *testing.T) {
+    tests := []struct {
+        name   string
+        modify func(*packet)
+        opts   Options
+        check  func(error) bool
+    }{
+        {
+            name:   "kiss-o'-death",
+            modify: func(p *packet) { p.Stratum = 0; p.ReferenceID = 0x52415445 },
+            check: func(err error) bool {
+                var kod *KissOfDeathError
+                return errors.As(err, &kod) && kod.Code == "RATE"
+            },
+        },
+        {
+            name:   "unsynchronised stratum",
+            modify: func(p *packet) { p.Stratum = 16 },
+            check: func(err error) bool {
+                var se *StratumError
+                return errors.As(err, &se) && se.Stratum == 16
+            },
+        },
+        {
+            name:   "leap not in sync",
+            modify: func(p *packet) { p.Leap = LeapNotInSync },
+            check: func(err error) bool {
+                var ns *NotSynchronizedError
+                return errors.As(err, &ns)
+            },
+        },
+        {
+            name:   "root distance",
+            modify: func(p *packet) { p.RootDispersion = toNtpShort(2 * time.Second) },
+            check: func(err error) bool {
+                var rd *RootDistanceError
+                return errors.As(err, &rd)
+            },
+        },
+        {
+            name: "round-trip time",
+            opts: Options{MaxRTT: time.Nanosecond},
+            check: func(err error) bool {
+                var re *RTTError
+                return errors.As(err, &re) && re.Max == time.Nanosecond
+            },
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            addr := startTestServer(t, 0, tt.modify)
+            tt.opts.Timeout = time.Second
+
+            response, err := Query(addr, &tt.opts)
+            if !tt.check(err) {
+                t.Errorf("Query() error = %v (%T)", err, err)
+            }
+            if response == nil {
+                t.Error("Query() returned no response with the validation error")
+            }
+        })
+    }
+}
+
+func TestQueryContext(t *testing.T) {
+    silent, err := net.ListenPacket("udp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatalf("listen: %v", err)
+    }
+    defer silent.Close()
+    addr := silent.LocalAddr().String()
+
+    ctx, cancel := context.WithCancel(context.Background())
+    time.AfterFunc(20*time.Millisecond, cancel)
+
+    start := time.Now()
+    if _, err := QueryContext(ctx, addr, &Options{Timeout: 5 * time.Second}); !errors.Is(err, context.Canceled) {
+        t.Errorf("QueryContext() error = %v, want %v", err, context.Canceled)
+    }
+    if elapsed := time.Since(start); elapsed > time.Second {
+        t.Errorf("QueryContext() returned after %v, want prompt cancellation", elapsed)
+    }
+
+    ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+    defer cancel()
+    if _, err := GetCurrentTimeContext(ctx, &Options{Server: addr}); !errors.Is(err, context.DeadlineExceeded) {
+        t.Errorf("GetCurrentTimeContext() error = %v, want %v", err, context.DeadlineExceeded)
+    }
+}
+
+// lateContext has a deadline but has not noticed it yet, as happens when
+// the socket deadline fires first.
+type lateContext struct {
+    context.Context
+    deadline time.Time
+}
+
+func (c lateContext) Deadline() (time.Time, bool) {
+    return c.deadline, true
+}
+
+func TestContextError(t *testing.T) {
+    passed := lateContext{context.Background(), time.Now().Add(-time.Millisecond)}
+    if err := contextError(passed, os.ErrDeadlineExceeded); err != context.DeadlineExceeded {
+        t.Errorf("contextError() past the deadline = %v, want %v", err, context.DeadlineExceeded)
+    }
+
+    pending := lateContext{context.Background(), time.Now().Add(time.Hour)}
+    if err := contextError(pending, os.ErrDeadlineExceeded); err != os.ErrDeadlineExceeded {
+        t.Errorf("contextError() before the deadline = %v, want %v", err, os.ErrDeadlineExceeded)
+    }
+}
+
+func TestGetCurrentTimeFrom(t *testing.T) {
+    servers := []string{
+        startTestServer(t, time.Second, nil),
+        startTestServer(t, time.Second, nil),
+        startTestServer(t, time.Hour, nil),
+    }
+
+    result, err := GetCurrentTimeFrom(servers, &Options{Timeout: time.Second})
+    if err != nil {
+        t.Fatalf("GetCurrentTimeFrom() unexpected error: %v", err)
+    }
+
+    if diff := result.Offset - time.Second; diff < -50*time.Millisecond || diff > 50*time.Millisecond {
+        t.Errorf("Offset = %v, want about 1s", result.Offset)
+    }
+    if result.Servers[2].Truechimer {
+        t.Error("server with one hour offset was not discarded")
+    }
+}
+
+func TestGetCurrentTimeFromLocalPort(t *testing.T) {
+    servers := []string{
+        startTestServer(t, time.Second, nil),
+        startTestServer(t, time.Second, nil),
+        startTestServer(t, time.Second, nil),
+    }
+
+    result, err := GetCurrentTimeFrom(servers, &Options{Timeout: time.Second, LocalPort: freePort(t)})
+    if err != nil {
+        t.Fatalf("GetCurrentTimeFrom() unexpected error: %v", err)
+    }
+    for _, sr := range result.Servers {
+        if sr.Err != nil {
+            t.Errorf("server %s: unexpected error: %v", sr.Server, sr.Err)
+        }
+    }
+}
+
+// freePort returns a UDP port that was free a moment ago.
+func freePort(t *testing.T) int {
+    t.Helper()
+
+    conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatalf("listen: %v", err)
+    }
+    defer conn.Close()
+    return conn.LocalAddr().(*net.UDPAddr).Port
+}
+
+// silentResolver sends name lookups to a DNS server that never answers
+// until the test ends.
+func silentResolver(t *testing.T) {
+    t.Helper()
+
+    silent, err := net.ListenPacket("udp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatalf("listen: %v", err)
+    }
+    t.Cleanup(func() { silent.Close() })
+
+    saved := net.DefaultResolver
+    net.DefaultResolver = &net.Resolver{
+        PreferGo: true,
+        Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+            var d net.Dialer
+            return d.DialContext(ctx, "udp", silent.LocalAddr().String())
+        },
+    }
+    t.Cleanup(func() { net.DefaultResolver = saved })
+}
@@ -0,0 +1,180 @@
+package ntp
+
+import (
+    "context"
+    "sync"
+    "time"
+)
+
+// Clock tells the current time. Services take a Clock so that tests can
+// replace the NTP-corrected clock with a FakeClock.
+type Clock interface {
+    Now() time.Time
+}
+
+// SystemClock is the uncorrected local clock.
+type SystemClock struct{}
+
+// Now returns time.Now.
+func (SystemClock) Now() time.Time {
+    return time.Now()
+}
+
+// SyncedClock is a Clock corrected by the offset measured with GetCurrentTime.
+// The correction is refreshed in the background; between refreshes the time
+// advances with the local monotonic clock, so steps of the system wall clock
+// do not affect it. Leap seconds are applied on time, or smeared when
+// Options.SmearWindow is set.
+type SyncedClock struct {
+    opts *Options
+
+    mu      sync.Mutex
+    at      time.Time
+    offset  time.Duration
+    leap    LeapSecond
+    hasLeap bool
+    stepped bool
+    lastErr error
+
+    done      chan struct{}
+    closeOnce sync.Once
+}
+
+// NewSyncedClock measures the offset once and then refreshes it every interval
+// until Close is called.
+func NewSyncedClock(opts *Options, interval time.Duration) (*SyncedClock, error) {
+    c := &SyncedClock{opts: opts, done: make(chan struct{})}
+    if err := c.refresh(); err != nil {
+        return nil, err
+    }
+
+    if interval <= 0 {
+        interval = defaultRefresh
+    }
+    go c.refreshLoop(interval)
+
+    return c, nil
+}
+
+// Now returns the corrected time. The result carries no monotonic reading,
+// so it can be compared with times from other clocks.
+func (c *SyncedClock) Now() time.Time {
+    c.mu.Lock()
+    at, offset := c.at, c.offset
+    leap, hasLeap, stepped := c.leap, c.hasLeap, c.stepped
+    c.mu.Unlock()
+
+    t := at.Round(0).Add(offset + time.Since(at))
+    if !hasLeap {
+        return t
+    }
+
+    // Bring t to a timescale that keeps counting through the leap second.
+    if stepped {
+        t = t.Add(leap.step())
+    }
+    if window := smearWindow(c.opts); window > 0 {
+        return smearContinuous(t, leap, window)
+    }
+    return utcFromContinuous(t, leap)
+}
+
+// Offset returns the correction measured by the last successful refresh.
+func (c *SyncedClock) Offset() time.Duration {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    return c.offset
+}
+
+// Err returns the error of the last refresh, or nil if it succeeded.
+func (c *SyncedClock) Err() error {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    return c.lastErr
+}
+
+// Close stops the background refresh. It is safe to call more than once.
+func (c *SyncedClock) Close() error {
+    c.closeOnce.Do(func() { close(c.done) })
+    return nil
+}
+
+func (c *SyncedClock) refreshLoop(interval time.Duration) {
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-c.done:
+            return
+        case <-ticker.C:
+            c.refresh()
+        }
+    }
+}
+
+// refresh keeps the previous correction when the query fails.
+// The offset is taken between monotonic readings, so wall clock steps do not affect it.
+// A known leap second is kept until its smear window is over, because
+// servers clear the leap indicator once it has passed.
+func (c *SyncedClock) refresh() error {
+    corrected, response, err := queryTime(context.Background(), c.opts)
+    at := time.Now()
+
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    c.lastErr = err
+    if err != nil {
+        return err
+    }
+
+    c.at = at
+    c.offset = corrected.Sub(at)
+
+    if leap, ok := leapFor(c.opts, response.Leap, corrected); ok {
+        c.leap, c.hasLeap = leap, true
+    } else if c.hasLeap && corrected.After(c.leap.Time.Add(smearWindow(c.opts)/2+time.Second)) {
+        c.hasLeap = false
+    }
+    c.stepped = c.hasLeap && !corrected.Before(c.leap.Time)
+
+    return nil
+}
+
+// FakeClock is a Clock for tests that only moves when told to.
+type FakeClock struct {
+    mu  sync.Mutex
+    now time.Time
+}
+
+// NewFakeClock returns a FakeClock set to now.
+func NewFakeClock(now time.Time) *FakeClock {
+    return &FakeClock{now: now}
+}
+
+// Now returns the time the clock is set to.
+func (c *FakeClock) Now() time.Time {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    return c.now
+}
+
+// Set sets the clock to t.
+func (c *FakeClock) Set(t time.Time) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    c.now = t
+}
+
+// Advance moves the clock forward by d.
+func (c *FakeClock) Advance(d time.Duration) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    c.now = c.now.Add(d)
+}
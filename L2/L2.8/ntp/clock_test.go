@@ -0,0 +1,41 @@
+package ntp
+
+import (
+    "testing"
+    "time"
+)
+
+func TestSyncedClock(t *testing.T) {
+    addr := startTestServer(t, -time.Hour, nil)
+
+    clock, err := NewSyncedClock(&Options{Server: addr, Timeout: time.Second}, time.Hour)
+    if err != nil {
+        t.Fatalf("NewSyncedClock() unexpected error: %v", err)
+    }
+    defer clock.Close()
+
+    if diff := clock.Offset() + time.Hour; diff < -50*time.Millisecond || diff > 50*time.Millisecond {
+        t.Errorf("Offset() = %v, want about -1h", clock.Offset())
+    }
+
+    first := clock.Now()
+    time.Sleep(20 * time.Millisecond)
+    if elapsed := clock.Now().Sub(first); elapsed < 20*time.Millisecond {
+        t.Errorf("clock advanced by %v, want at least 20ms", elapsed)
+    }
+
+    if err := clock.Close(); err != nil {
+        t.Errorf("Close() unexpected error: %v", err)
+    }
+}
+
+func TestFakeClock(t *testing.T) {
+    start := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+    var clock Clock = NewFakeClock(start)
+
+    clock.(*FakeClock).Advance(time.Minute)
+
+    if got := clock.Now(); !got.Equal(start.Add(time.Minute)) {
+        t.Errorf("Now() = %v, want %v", got, start.Add(time.Minute))
+    }
+}
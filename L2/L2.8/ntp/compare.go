@@ -0,0 +1,102 @@
+package ntp
+
+import (
+    "context"
+    "fmt"
+    "net"
+    "strconv"
+    "sync"
+    "time"
+)
+
+// Comparison is the reply of one address found behind a host name.
+// Response may be set together with Err when the reply failed validation.
+type Comparison struct {
+    Host     string
+    Address  string
+    Response *Response
+    Err      error
+}
+
+// CompareServers resolves every address behind hosts and queries each of them concurrently,
+// or one after another when a LocalPort is set.
+// A host that cannot be resolved yields a single Comparison with the error.
+func CompareServers(ctx context.Context, hosts []string, opts *Options) []Comparison {
+    var comparisons []Comparison
+    for _, host := range hosts {
+        addrs, err := resolve(ctx, host, queryTimeout(opts))
+        if err != nil {
+            comparisons = append(comparisons, Comparison{Host: host, Address: host, Err: err})
+            continue
+        }
+        for _, addr := range addrs {
+            comparisons = append(comparisons, Comparison{Host: host, Address: addr})
+        }
+    }
+
+    var wg sync.WaitGroup
+    for i := range comparisons {
+        c := &comparisons[i]
+        if c.Err != nil {
+            continue
+        }
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            c.Response, c.Err = QueryContext(ctx, c.Address, opts)
+        }()
+        if !concurrent(opts) {
+            wg.Wait()
+        }
+    }
+    wg.Wait()
+
+    return comparisons
+}
+
+// resolve returns host:port for every address of host, giving up after
+// timeout like a query would.
+func resolve(ctx context.Context, host string, timeout time.Duration) ([]string, error) {
+    port := strconv.Itoa(defaultPort)
+    if h, p, err := net.SplitHostPort(host); err == nil {
+        host, port = h, p
+    }
+
+    timeoutCtx, cancel := context.WithTimeout(ctx, timeout)
+    defer cancel()
+
+    ips, err := net.DefaultResolver.LookupHost(timeoutCtx, host)
+    if err != nil {
+        return nil, contextError(ctx, err)
+    }
+
+    addrs := make([]string, len(ips))
+    for i, ip := range ips {
+        addrs[i] = net.JoinHostPort(ip, port)
+    }
+    return addrs, nil
+}
+
+// ReferenceString returns the reference ID as text: the kiss code for
+// stratum 0, the source name for stratum 1 and an IPv4 address otherwise.
+func (r *Response) ReferenceString() string {
+    if r.Stratum <= 1 {
+        return refIDString(r.ReferenceID)
+    }
+
+    id := r.ReferenceID
+    return fmt.Sprintf("%d.%d.%d.%d", byte(id>>24), byte(id>>16), byte(id>>8), byte(id))
+}
+
+func (l LeapIndicator) String() string {
+    switch l {
+    case LeapNoWarning:
+        return "none"
+    case LeapAddSecond:
+        return "insert"
+    case LeapDelSecond:
+        return "delete"
+    default:
+        return "unsynchronized"
+    }
+}
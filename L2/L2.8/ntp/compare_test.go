@@ -0,0 +1,57 @@
+package ntp
+
+import (
+    "context"
+    "testing"
+    "time"
+)
+
+func TestCompareServers(t *testing.T) {
+    good := startServer(t, &Server{Stratum: 1})
+    bad := startTestServer(t, 0, func(p *packet) { p.Leap = LeapNotInSync })
+
+    comparisons := CompareServers(context.Background(), []string{good, bad, "invalid host name:123"},
+        &Options{Timeout: time.Second})
+
+    if len(comparisons) != 3 {
+        t.Fatalf("CompareServers() returned %d comparisons, want 3", len(comparisons))
+    }
+
+    if c := comparisons[0]; c.Err != nil || c.Response.ReferenceString() != "LOCL" {
+        t.Errorf("good server: err = %v, response = %+v", c.Err, c.Response)
+    }
+    if c := comparisons[1]; c.Err == nil || c.Response == nil || c.Response.Leap.String() != "unsynchronized" {
+        t.Errorf("unsynchronized server: err = %v, response = %+v", c.Err, c.Response)
+    }
+    if c := comparisons[1]; c.Response != nil && c.Response.ReferenceString() != "127.0.0.1" {
+        t.Errorf("ReferenceString() = %q, want 127.0.0.1", c.Response.ReferenceString())
+    }
+    if c := comparisons[2]; c.Err == nil {
+        t.Error("unresolvable host: expected error, got none")
+    }
+}
+
+func TestCompareServersLookupTimeout(t *testing.T) {
+    silentResolver(t)
+
+    start := time.Now()
+    comparisons := CompareServers(context.Background(), []string{"ntp.example.com"},
+        &Options{Timeout: 200 * time.Millisecond})
+    if len(comparisons) != 1 || comparisons[0].Err == nil {
+        t.Fatalf("CompareServers() = %+v, want one lookup error", comparisons)
+    }
+    if elapsed := time.Since(start); elapsed > time.Second {
+        t.Errorf("CompareServers() returned after %v, want the lookup bounded by the timeout", elapsed)
+    }
+}
+
+func TestCompareServersLocalPort(t *testing.T) {
+    servers := []string{startServer(t, &Server{}), startServer(t, &Server{})}
+
+    opts := &Options{Timeout: time.Second, LocalPort: freePort(t)}
+    for _, c := range CompareServers(context.Background(), servers, opts) {
+        if c.Err != nil {
+            t.Errorf("%s: unexpected error: %v", c.Address, c.Err)
+        }
+    }
+}
@@ -0,0 +1,180 @@
+package ntp
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "sort"
+    "sync"
+    "time"
+)
+
+// ErrNoConsensus is returned when no majority of servers agree on the time.
+var ErrNoConsensus = errors.New("no majority of NTP servers agree on the time")
+
+// ServerResult holds the outcome of querying a single server.
+type ServerResult struct {
+    Server     string
+    Offset     time.Duration
+    RTT        time.Duration
+    Distance   time.Duration
+    Stratum    uint8
+    Leap       LeapIndicator
+    Truechimer bool
+    Err        error
+}
+
+// Result holds the combined outcome of querying several servers.
+// Leap is the leap indicator reported by most truechimers.
+type Result struct {
+    Time    time.Time
+    Offset  time.Duration
+    Leap    LeapIndicator
+    Servers []ServerResult
+}
+
+// GetCurrentTimeFrom queries servers concurrently, or one after another
+// when a LocalPort is set, discards falsetickers and returns the time
+// corrected by the mean offset of the remaining servers.
+// The per-server diagnostics are returned even when an error occurs.
+func GetCurrentTimeFrom(servers []string, opts *Options) (*Result, error) {
+    return GetCurrentTimeFromContext(context.Background(), servers, opts)
+}
+
+// GetCurrentTimeFromContext is like GetCurrentTimeFrom but can be cancelled through ctx.
+func GetCurrentTimeFromContext(ctx context.Context, servers []string, opts *Options) (*Result, error) {
+    if len(servers) == 0 {
+        return nil, errors.New("no NTP servers given")
+    }
+
+    result := &Result{Servers: make([]ServerResult, len(servers))}
+
+    var wg sync.WaitGroup
+    for i, server := range servers {
+        wg.Add(1)
+        go func(i int, server string) {
+            defer wg.Done()
+            result.Servers[i] = queryServer(ctx, server, opts)
+        }(i, server)
+        if !concurrent(opts) {
+            wg.Wait()
+        }
+    }
+    wg.Wait()
+
+    offset, err := selectOffset(result.Servers)
+    if err != nil {
+        return result, err
+    }
+
+    result.Offset = offset
+    result.Leap = majorityLeap(result.Servers)
+    result.Time = ApplyLeap(time.Now().Add(offset), result.Leap, opts)
+
+    return result, nil
+}
+
+func queryServer(ctx context.Context, server string, opts *Options) ServerResult {
+    sr := ServerResult{Server: server}
+
+    response, err := QueryContext(ctx, server, opts)
+    if err != nil {
+        sr.Err = fmt.Errorf("failed to query NTP server %s: %w", server, err)
+        return sr
+    }
+
+    sr.Offset = response.ClockOffset
+    sr.RTT = response.RTT
+    sr.Distance = response.RootDistance
+    sr.Stratum = response.Stratum
+    sr.Leap = response.Leap
+
+    return sr
+}
+
+func majorityLeap(results []ServerResult) LeapIndicator {
+    var votes [4]int
+    for _, r := range results {
+        if r.Truechimer {
+            votes[r.Leap&3]++
+        }
+    }
+
+    leap := LeapNoWarning
+    for li, n := range votes {
+        if n > votes[leap] {
+            leap = LeapIndicator(li)
+        }
+    }
+    return leap
+}
+
+// selectOffset marks the truechimers among the answered servers using
+// Marzullo's algorithm and returns the mean of their offsets.
+// Each server contributes the interval offset ± root distance, and the
+// truechimers must be a majority of all servers, failed ones included.
+func selectOffset(results []ServerResult) (time.Duration, error) {
+    type edge struct {
+        value time.Duration
+        start bool
+    }
+
+    var edges []edge
+    var firstErr error
+    answered := 0
+    for _, r := range results {
+        if r.Err != nil {
+            if firstErr == nil {
+                firstErr = r.Err
+            }
+            continue
+        }
+        answered++
+        edges = append(edges,
+            edge{r.Offset - r.Distance, true},
+            edge{r.Offset + r.Distance, false})
+    }
+
+    if answered == 0 {
+        return 0, firstErr
+    }
+
+    sort.Slice(edges, func(i, j int) bool {
+        if edges[i].value != edges[j].value {
+            return edges[i].value < edges[j].value
+        }
+        return edges[i].start && !edges[j].start
+    })
+
+    var low, high time.Duration
+    best, count := 0, 0
+    for i, e := range edges {
+        if !e.start {
+            count--
+            continue
+        }
+        count++
+        if count > best {
+            best = count
+            low, high = e.value, edges[i+1].value
+        }
+    }
+
+    if best <= len(results)/2 {
+        return 0, ErrNoConsensus
+    }
+
+    var sum time.Duration
+    chimers := 0
+    for i := range results {
+        r := &results[i]
+        if r.Err != nil || r.Offset+r.Distance < low || r.Offset-r.Distance > high {
+            continue
+        }
+        r.Truechimer = true
+        sum += r.Offset
+        chimers++
+    }
+
+    return sum / time.Duration(chimers), nil
+}
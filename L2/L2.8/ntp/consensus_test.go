@@ -0,0 +1,101 @@
+package ntp
+
+import (
+    "errors"
+    "testing"
+    "time"
+)
+
+func TestSelectOffset(t *testing.T) {
+    ms := time.Millisecond
+
+    tests := []struct {
+        name       string
+        results    []ServerResult
+        expected   time.Duration
+        truechimer []bool
+        err        error
+    }{
+        {
+            name: "single server",
+            results: []ServerResult{
+                {Offset: 10 * ms, Distance: 5 * ms},
+            },
+            expected:   10 * ms,
+            truechimer: []bool{true},
+        },
+        {
+            name: "one falseticker",
+            results: []ServerResult{
+                {Offset: 10 * ms, Distance: 5 * ms},
+                {Offset: 12 * ms, Distance: 5 * ms},
+                {Offset: 900 * ms, Distance: 5 * ms},
+            },
+            expected:   11 * ms,
+            truechimer: []bool{true, true, false},
+        },
+        {
+            name: "failed server ignored",
+            results: []ServerResult{
+                {Offset: 10 * ms, Distance: 5 * ms},
+                {Err: errors.New("timeout")},
+                {Offset: 14 * ms, Distance: 5 * ms},
+            },
+            expected:   12 * ms,
+            truechimer: []bool{true, false, true},
+        },
+        {
+            name: "only one of three answered",
+            results: []ServerResult{
+                {Err: errors.New("timeout")},
+                {Offset: 10 * ms, Distance: 5 * ms},
+                {Err: errors.New("timeout")},
+            },
+            err:        ErrNoConsensus,
+            truechimer: []bool{false, false, false},
+        },
+        {
+            name: "no majority",
+            results: []ServerResult{
+                {Offset: 10 * ms, Distance: 5 * ms},
+                {Offset: 900 * ms, Distance: 5 * ms},
+            },
+            err:        ErrNoConsensus,
+            truechimer: []bool{false, false},
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            offset, err := selectOffset(tt.results)
+
+            if tt.err != nil {
+                if !errors.Is(err, tt.err) {
+                    t.Errorf("selectOffset() error = %v, want %v", err, tt.err)
+                }
+            } else {
+                if err != nil {
+                    t.Fatalf("selectOffset() unexpected error: %v", err)
+                }
+                if offset != tt.expected {
+                    t.Errorf("selectOffset() = %v, want %v", offset, tt.expected)
+                }
+            }
+
+            for i, want := range tt.truechimer {
+                if tt.results[i].Truechimer != want {
+                    t.Errorf("server %d truechimer = %v, want %v", i, tt.results[i].Truechimer, want)
+                }
+            }
+        })
+    }
+}
+
+func TestSelectOffsetAllFailed(t *testing.T) {
+    queryErr := errors.New("timeout")
+    results := []ServerResult{{Err: queryErr}, {Err: errors.New("refused")}}
+
+    if _, err := selectOffset(results); !errors.Is(err, queryErr) {
+        t.Errorf("selectOffset() error = %v, want %v", err, queryErr)
+    }
+}
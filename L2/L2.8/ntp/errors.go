@@ -0,0 +1,92 @@
+package ntp
+
+import (
+    "fmt"
+    "time"
+)
+
+const (
+    maxStratum             = 16
+    defaultMaxRootDistance = 1500 * time.Millisecond
+    defaultMaxRTT          = time.Second
+)
+
+// StratumError reports a reply with an unspecified (0) or unsynchronised (16) stratum.
+type StratumError struct {
+    Stratum uint8
+}
+
+func (e *StratumError) Error() string {
+    return fmt.Sprintf("invalid NTP stratum %d", e.Stratum)
+}
+
+// NotSynchronizedError reports a server whose leap indicator says its clock is not synchronised.
+type NotSynchronizedError struct{}
+
+func (e *NotSynchronizedError) Error() string {
+    return "NTP server clock is not synchronized"
+}
+
+// KissOfDeathError reports a Kiss-o'-Death reply. RATE asks the client to
+// reduce its polling rate; DENY and RSTR mean the server must not be queried again.
+type KissOfDeathError struct {
+    Code string
+}
+
+func (e *KissOfDeathError) Error() string {
+    return fmt.Sprintf("NTP kiss-o'-death %q", e.Code)
+}
+
+// RootDistanceError reports a root distance above the configured maximum.
+type RootDistanceError struct {
+    Distance time.Duration
+    Max      time.Duration
+}
+
+func (e *RootDistanceError) Error() string {
+    return fmt.Sprintf("NTP root distance %v exceeds %v", e.Distance, e.Max)
+}
+
+// RTTError reports a round-trip time above the configured maximum.
+type RTTError struct {
+    RTT time.Duration
+    Max time.Duration
+}
+
+func (e *RTTError) Error() string {
+    return fmt.Sprintf("NTP round-trip time %v exceeds %v", e.RTT, e.Max)
+}
+
+// validate checks a reply against RFC 4330 and the limits in opts.
+func validate(r *Response, opts *Options) error {
+    if r.Stratum == 0 {
+        if r.KissCode != "" {
+            return &KissOfDeathError{Code: r.KissCode}
+        }
+        return &StratumError{Stratum: r.Stratum}
+    }
+    if r.Stratum >= maxStratum {
+        return &StratumError{Stratum: r.Stratum}
+    }
+    if r.Leap == LeapNotInSync {
+        return &NotSynchronizedError{}
+    }
+
+    maxDistance := opts.MaxRootDistance
+    if maxDistance <= 0 {
+        maxDistance = defaultMaxRootDistance
+    }
+    if r.RootDistance > maxDistance {
+        return &RootDistanceError{Distance: r.RootDistance, Max: maxDistance}
+    }
+
+    maxRTT := opts.MaxRTT
+    if maxRTT <= 0 {
+        maxRTT = defaultMaxRTT
+    }
+    if r.RTT > maxRTT {
+        return &RTTError{RTT: r.RTT, Max: maxRTT}
+    }
+
+    return nil
+}
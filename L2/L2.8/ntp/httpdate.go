@@ -0,0 +1,63 @@
+package ntp
+
+import (
+    "context"
+    "errors"
+    "net/http"
+    "time"
+)
+
+// HTTPDateSource measures the offset from the Date header of an HTTP response.
+// The header has a resolution of one second, so the uncertainty is at least half a second.
+type HTTPDateSource struct {
+    URL    string
+    Client *http.Client
+}
+
+// Name returns "http:" followed by the URL.
+func (s *HTTPDateSource) Name() string {
+    return "http:" + s.URL
+}
+
+// Measure sends a HEAD request and compares the Date header with the
+// midpoint of the exchange.
+func (s *HTTPDateSource) Measure(ctx context.Context) (*Reading, error) {
+    client := s.Client
+    if client == nil {
+        client = http.DefaultClient
+    }
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodHead, s.URL, nil)
+    if err != nil {
+        return nil, err
+    }
+
+    sent := time.Now()
+    resp, err := client.Do(req)
+    if err != nil {
+        return nil, err
+    }
+    resp.Body.Close()
+    rtt := time.Since(sent)
+
+    header := resp.Header.Get("Date")
+    if header == "" {
+        return nil, errors.New("HTTP response has no Date header")
+    }
+    date, err := http.ParseTime(header)
+    if err != nil {
+        return nil, err
+    }
+
+    // The server truncated its clock to the second: assume the middle of it.
+    remote := date.Add(500 * time.Millisecond)
+    local := sent.Add(rtt / 2)
+    offset := remote.Sub(local.Round(0))
+
+    return &Reading{
+        Source:      s.Name(),
+        Time:        time.Now().Add(offset),
+        Offset:      offset,
+        Uncertainty: 500*time.Millisecond + rtt/2,
+    }, nil
+}
@@ -0,0 +1,194 @@
+package ntp
+
+import (
+    "bufio"
+    "fmt"
+    "io"
+    "os"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// LeapSecond is a leap second taking effect at Time, the UTC midnight ending
+// the day a second is inserted into (Delta 1) or deleted from (Delta -1).
+type LeapSecond struct {
+    Time  time.Time
+    Delta int
+}
+
+// LeapEntry is a line of a leap-seconds list: from Time on, TAI - UTC is TAIOffset seconds.
+type LeapEntry struct {
+    Time      time.Time
+    TAIOffset int
+}
+
+// LeapTable is a parsed leap-seconds list.
+type LeapTable struct {
+    Entries []LeapEntry
+    Expires time.Time
+}
+
+// LoadLeapSeconds reads a file in the IETF leap-seconds.list format.
+func LoadLeapSeconds(path string) (*LeapTable, error) {
+    file, err := os.Open(path)
+    if err != nil {
+        return nil, err
+    }
+    defer file.Close()
+
+    return ParseLeapSeconds(file)
+}
+
+// ParseLeapSeconds parses the IETF leap-seconds.list format: "NTP-seconds
+// TAI-offset" lines in ascending order and the "#@" expiry line.
+func ParseLeapSeconds(r io.Reader) (*LeapTable, error) {
+    table := &LeapTable{}
+
+    scanner := bufio.NewScanner(r)
+    lineNum := 0
+    for scanner.Scan() {
+        lineNum++
+        line := scanner.Text()
+
+        if rest, ok := strings.CutPrefix(line, "#@"); ok {
+            sec, err := strconv.ParseUint(strings.TrimSpace(rest), 10, 64)
+            if err != nil {
+                return nil, fmt.Errorf("leap seconds line %d: invalid expiry", lineNum)
+            }
+            table.Expires = ntpSecondsTime(sec)
+            continue
+        }
+        if i := strings.IndexByte(line, '#'); i >= 0 {
+            line = line[:i]
+        }
+        fields := strings.Fields(line)
+        if len(fields) == 0 {
+            continue
+        }
+        if len(fields) != 2 {
+            return nil, fmt.Errorf("leap seconds line %d: want \"seconds offset\"", lineNum)
+        }
+
+        sec, err := strconv.ParseUint(fields[0], 10, 64)
+        if err != nil {
+            return nil, fmt.Errorf("leap seconds line %d: invalid time %q", lineNum, fields[0])
+        }
+        offset, err := strconv.Atoi(fields[1])
+        if err != nil {
+            return nil, fmt.Errorf("leap seconds line %d: invalid offset %q", lineNum, fields[1])
+        }
+
+        entry := LeapEntry{Time: ntpSecondsTime(sec), TAIOffset: offset}
+        if n := len(table.Entries); n > 0 && !entry.Time.After(table.Entries[n-1].Time) {
+            return nil, fmt.Errorf("leap seconds line %d: entries out of order", lineNum)
+        }
+        table.Entries = append(table.Entries, entry)
+    }
+
+    return table, scanner.Err()
+}
+
+// Next returns the first leap second after t.
+func (lt *LeapTable) Next(t time.Time) (LeapSecond, bool) {
+    for i := 1; i < len(lt.Entries); i++ {
+        entry := lt.Entries[i]
+        if entry.Time.After(t) {
+            return LeapSecond{Time: entry.Time, Delta: entry.TAIOffset - lt.Entries[i-1].TAIOffset}, true
+        }
+    }
+    return LeapSecond{}, false
+}
+
+// PendingLeap returns the leap second announced by a leap indicator received
+// at t. Per RFC 4330 it takes effect at the end of the current UTC day.
+func PendingLeap(li LeapIndicator, t time.Time) (LeapSecond, bool) {
+    var delta int
+    switch li {
+    case LeapAddSecond:
+        delta = 1
+    case LeapDelSecond:
+        delta = -1
+    default:
+        return LeapSecond{}, false
+    }
+
+    midnight := t.UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+    return LeapSecond{Time: midnight, Delta: delta}, true
+}
+
+// Smear spreads the leap second linearly over a window centred on it, so the
+// returned time never steps. t is UTC as served by NTP; the inserted second
+// repeats UTC times and is smeared as its first occurrence.
+func Smear(t time.Time, leap LeapSecond, window time.Duration) time.Time {
+    if !t.Before(leap.Time) {
+        t = t.Add(leap.step())
+    }
+    return smearContinuous(t, leap, window)
+}
+
+// step is the amount by which a clock counting elapsed seconds runs ahead of UTC after the leap.
+func (l LeapSecond) step() time.Duration {
+    return time.Duration(l.Delta) * time.Second
+}
+
+// smearContinuous smears t given on a timescale without the leap, one that
+// keeps counting elapsed seconds through it.
+func smearContinuous(t time.Time, leap LeapSecond, window time.Duration) time.Time {
+    start := leap.Time.Add(-window / 2)
+    if t.Before(start) {
+        return t
+    }
+
+    elapsed := t.Sub(start)
+    if elapsed >= window {
+        return t.Add(-leap.step())
+    }
+    return t.Add(-time.Duration(float64(leap.step()) * float64(elapsed) / float64(window)))
+}
+
+// utcFromContinuous converts t given on a timescale without the leap to UTC.
+// An inserted second repeats the last second of the day.
+func utcFromContinuous(t time.Time, leap LeapSecond) time.Time {
+    stepAt := leap.Time
+    if leap.Delta < 0 {
+        stepAt = stepAt.Add(leap.step())
+    }
+    if t.Before(stepAt) {
+        return t
+    }
+    return t.Add(-leap.step())
+}
+
+// leapFor returns the leap second relevant at t: the next one from
+// opts.LeapSeconds, or the one announced by the leap indicator.
+func leapFor(opts *Options, li LeapIndicator, t time.Time) (LeapSecond, bool) {
+    if opts != nil && opts.LeapSeconds != nil {
+        return opts.LeapSeconds.Next(t.Add(-smearWindow(opts) / 2))
+    }
+    return PendingLeap(li, t)
+}
+
+// ApplyLeap smears t around a nearby leap second when opts.SmearWindow is set.
+func ApplyLeap(t time.Time, li LeapIndicator, opts *Options) time.Time {
+    if smearWindow(opts) == 0 {
+        return t
+    }
+
+    leap, ok := leapFor(opts, li, t)
+    if !ok {
+        return t
+    }
+    return Smear(t, leap, opts.SmearWindow)
+}
+
+func smearWindow(opts *Options) time.Duration {
+    if opts == nil || opts.SmearWindow < 0 {
+        return 0
+    }
+    return opts.SmearWindow
+}
+
+func ntpSecondsTime(sec uint64) time.Time {
+    return time.Unix(int64(sec)-ntpEpochOffset, 0).UTC()
+}
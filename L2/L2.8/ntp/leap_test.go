@@ -0,0 +1,130 @@
+package ntp
+
+import (
+    "strings"
+    "testing"
+    "time"
+)
+
+const leapSecondsList = `#	Updated through IERS Bulletin C
+#$	 3945196800
+#@	 3960921600
+#
+2272060800	10	# 1 Jan 1972
+2287785600	11	# 1 Jul 1972
+3644697600	36	# 1 Jul 2015
+3692217600	37	# 1 Jan 2017
+`
+
+func TestParseLeapSeconds(t *testing.T) {
+    table, err := ParseLeapSeconds(strings.NewReader(leapSecondsList))
+    if err != nil {
+        t.Fatalf("ParseLeapSeconds() unexpected error: %v", err)
+    }
+
+    if len(table.Entries) != 4 {
+        t.Fatalf("ParseLeapSeconds() returned %d entries, want 4", len(table.Entries))
+    }
+    if want := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC); !table.Entries[3].Time.Equal(want) {
+        t.Errorf("entry time = %v, want %v", table.Entries[3].Time, want)
+    }
+    if want := time.Date(2025, 7, 8, 0, 0, 0, 0, time.UTC); !table.Expires.Equal(want) {
+        t.Errorf("Expires = %v, want %v", table.Expires, want)
+    }
+
+    leap, ok := table.Next(time.Date(2016, 6, 1, 0, 0, 0, 0, time.UTC))
+    if !ok || leap.Delta != 1 || !leap.Time.Equal(time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)) {
+        t.Errorf("Next() = %+v, %v", leap, ok)
+    }
+    if _, ok := table.Next(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)); ok {
+        t.Error("Next() after the last entry returned a leap second")
+    }
+
+    if _, err := ParseLeapSeconds(strings.NewReader("3692217600 37\n2272060800 10\n")); err == nil {
+        t.Error("ParseLeapSeconds() of unordered entries expected error, got none")
+    }
+}
+
+func TestPendingLeap(t *testing.T) {
+    at := time.Date(2016, 12, 31, 15, 0, 0, 0, time.UTC)
+
+    leap, ok := PendingLeap(LeapAddSecond, at)
+    if !ok || leap.Delta != 1 || !leap.Time.Equal(time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)) {
+        t.Errorf("PendingLeap() = %+v, %v", leap, ok)
+    }
+    if _, ok := PendingLeap(LeapNoWarning, at); ok {
+        t.Error("PendingLeap() without warning returned a leap second")
+    }
+}
+
+func TestSmear(t *testing.T) {
+    l := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+    leap := LeapSecond{Time: l, Delta: 1}
+    window := 24 * time.Hour
+
+    tests := []struct {
+        name     string
+        utc      time.Time
+        expected time.Time
+    }{
+        {"before window", l.Add(-13 * time.Hour), l.Add(-13 * time.Hour)},
+        {"window start", l.Add(-12 * time.Hour), l.Add(-12 * time.Hour)},
+        {"quarter", l.Add(-6 * time.Hour), l.Add(-6*time.Hour - 250*time.Millisecond)},
+        {"just before leap", l.Add(-time.Second), l.Add(-time.Second - 499988426*time.Nanosecond)},
+        {"leap", l, l.Add(time.Second - 500011574*time.Nanosecond)},
+        {"three quarters", l.Add(6*time.Hour - time.Second), l.Add(6*time.Hour - 750*time.Millisecond)},
+        {"after window", l.Add(13 * time.Hour), l.Add(13 * time.Hour)},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            got := Smear(tt.utc, leap, window)
+            if diff := got.Sub(tt.expected); diff < -time.Microsecond || diff > time.Microsecond {
+                t.Errorf("Smear(%v) = %v, want %v", tt.utc, got, tt.expected)
+            }
+        })
+    }
+}
+
+func TestUTCFromContinuous(t *testing.T) {
+    l := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+
+    insert := LeapSecond{Time: l, Delta: 1}
+    if got := utcFromContinuous(l.Add(500*time.Millisecond), insert); !got.Equal(l.Add(-500 * time.Millisecond)) {
+        t.Errorf("inserted second maps to %v, want the repeated 23:59:59.5", got)
+    }
+
+    remove := LeapSecond{Time: l, Delta: -1}
+    if got := utcFromContinuous(l.Add(-time.Second), remove); !got.Equal(l) {
+        t.Errorf("deleted second maps to %v, want midnight", got)
+    }
+}
+
+func TestSyncedClockSmear(t *testing.T) {
+    now := time.Now().UTC()
+    midnight := now.Truncate(24 * time.Hour).Add(24 * time.Hour)
+    offset := midnight.Add(-time.Minute).Sub(now)
+
+    addr := startTestServer(t, offset, func(p *packet) { p.Leap = LeapAddSecond })
+    opts := &Options{Server: addr, Timeout: time.Second, SmearWindow: 10 * time.Minute}
+
+    clock, err := NewSyncedClock(opts, time.Hour)
+    if err != nil {
+        t.Fatalf("NewSyncedClock() unexpected error: %v", err)
+    }
+    defer clock.Close()
+
+    // One minute before the leap, 4 of the 10 minutes are smeared: 0.4s behind.
+    want := offset - 400*time.Millisecond
+    if diff := clock.Now().Sub(time.Now()) - want; diff < -50*time.Millisecond || diff > 50*time.Millisecond {
+        t.Errorf("smeared clock is off by %v, want %v", clock.Now().Sub(time.Now()), want)
+    }
+
+    smeared, err := GetCurrentTime(opts)
+    if err != nil {
+        t.Fatalf("GetCurrentTime() unexpected error: %v", err)
+    }
+    if diff := smeared.Sub(time.Now()) - want; diff < -50*time.Millisecond || diff > 50*time.Millisecond {
+        t.Errorf("GetCurrentTime() is off by %v, want %v", smeared.Sub(time.Now()), want)
+    }
+}
@@ -0,0 +1,183 @@
+package ntp
+
+import (
+    "fmt"
+    "math"
+    "net/http"
+    "sync"
+    "time"
+)
+
+const (
+    defaultInterval    = time.Minute
+    defaultHistorySize = 64
+)
+
+// Sample is a single measurement taken by Monitor.
+type Sample struct {
+    Time   time.Time
+    Offset time.Duration
+    Delay  time.Duration
+    Err    error
+}
+
+// Stats summarises the successful samples in the monitor history.
+// Latest is the most recent successful sample, LastErr the error of
+// the most recent poll if it failed.
+type Stats struct {
+    Latest     Sample
+    LastErr    error
+    Count      int
+    MeanOffset time.Duration
+    MinDelay   time.Duration
+    MaxDelay   time.Duration
+    Jitter     time.Duration
+    Polls      uint64
+    Failures   uint64
+}
+
+// Monitor polls an NTP server on an interval and keeps a rolling history
+// of offset and delay. It serves the statistics in Prometheus text format.
+type Monitor struct {
+    Options     *Options
+    Interval    time.Duration
+    HistorySize int
+
+    mu       sync.Mutex
+    history  []Sample
+    last     Sample
+    polls    uint64
+    failures uint64
+}
+
+// Run polls immediately and then every m.Interval until done is closed.
+func (m *Monitor) Run(done <-chan struct{}) {
+    interval := m.Interval
+    if interval <= 0 {
+        interval = defaultInterval
+    }
+
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+
+    m.Poll()
+    for {
+        select {
+        case <-done:
+            return
+        case <-ticker.C:
+            m.Poll()
+        }
+    }
+}
+
+// Poll takes one sample and records it in the history.
+func (m *Monitor) Poll() Sample {
+    sample := Sample{Time: time.Now()}
+
+    response, err := Query(serverAddr(m.Options), m.Options)
+    if err != nil {
+        sample.Err = err
+    } else {
+        sample.Offset = response.ClockOffset
+        sample.Delay = response.RTT
+    }
+
+    m.record(sample)
+
+    return sample
+}
+
+func (m *Monitor) record(sample Sample) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    m.polls++
+    m.last = sample
+    if sample.Err != nil {
+        m.failures++
+        return
+    }
+
+    size := m.HistorySize
+    if size <= 0 {
+        size = defaultHistorySize
+    }
+    m.history = append(m.history, sample)
+    if len(m.history) > size {
+        m.history = m.history[len(m.history)-size:]
+    }
+}
+
+// History returns a copy of the successful samples, oldest first.
+func (m *Monitor) History() []Sample {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    return append([]Sample(nil), m.history...)
+}
+
+// Stats computes the statistics of the current history.
+// Jitter is the RMS of the differences between successive offsets.
+func (m *Monitor) Stats() Stats {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    stats := Stats{
+        LastErr:  m.last.Err,
+        Count:    len(m.history),
+        Polls:    m.polls,
+        Failures: m.failures,
+    }
+    if len(m.history) == 0 {
+        return stats
+    }
+    stats.Latest = m.history[len(m.history)-1]
+
+    var sum time.Duration
+    var squares float64
+    stats.MinDelay = m.history[0].Delay
+    for i, s := range m.history {
+        sum += s.Offset
+        stats.MinDelay = min(stats.MinDelay, s.Delay)
+        stats.MaxDelay = max(stats.MaxDelay, s.Delay)
+        if i > 0 {
+            diff := float64(s.Offset - m.history[i-1].Offset)
+            squares += diff * diff
+        }
+    }
+    stats.MeanOffset = sum / time.Duration(len(m.history))
+    if len(m.history) > 1 {
+        stats.Jitter = time.Duration(math.Sqrt(squares / float64(len(m.history)-1)))
+    }
+
+    return stats
+}
+
+// ServeHTTP writes the monitor statistics in Prometheus text format.
+func (m *Monitor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    stats := m.Stats()
+    server := serverAddr(m.Options)
+
+    w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+    writeMetric(w, "ntp_polls_total", "counter", "Number of NTP polls.", server, float64(stats.Polls))
+    writeMetric(w, "ntp_poll_failures_total", "counter", "Number of failed NTP polls.", server, float64(stats.Failures))
+    if stats.Count == 0 {
+        return
+    }
+
+    writeMetric(w, "ntp_offset_seconds", "gauge", "Last measured clock offset.", server, stats.Latest.Offset.Seconds())
+    writeMetric(w, "ntp_delay_seconds", "gauge", "Last measured round-trip delay.", server, stats.Latest.Delay.Seconds())
+    writeMetric(w, "ntp_offset_mean_seconds", "gauge", "Mean clock offset over the history.", server, stats.MeanOffset.Seconds())
+    writeMetric(w, "ntp_delay_min_seconds", "gauge", "Minimum round-trip delay over the history.", server, stats.MinDelay.Seconds())
+    writeMetric(w, "ntp_delay_max_seconds", "gauge", "Maximum round-trip delay over the history.", server, stats.MaxDelay.Seconds())
+    writeMetric(w, "ntp_jitter_seconds", "gauge", "RMS of successive offset differences.", server, stats.Jitter.Seconds())
+    writeMetric(w, "ntp_history_samples", "gauge", "Number of samples in the history.", server, float64(stats.Count))
+    writeMetric(w, "ntp_last_success_timestamp_seconds", "gauge", "Time of the last successful poll.", server,
+        float64(stats.Latest.Time.UnixNano())/1e9)
+}
+
+func writeMetric(w http.ResponseWriter, name, kind, help, server string, value float64) {
+    fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s{server=%q} %g\n", name, help, name, kind, name, server, value)
+}
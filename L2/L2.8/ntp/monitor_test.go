@@ -0,0 +1,69 @@
+package ntp
+
+import (
+    "errors"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestMonitorStats(t *testing.T) {
+    ms := time.Millisecond
+    m := &Monitor{HistorySize: 3}
+
+    m.record(Sample{Offset: 100 * ms, Delay: 20 * ms})
+    m.record(Sample{Offset: 10 * ms, Delay: 30 * ms})
+    m.record(Sample{Err: errors.New("timeout")})
+    m.record(Sample{Offset: 13 * ms, Delay: 10 * ms})
+    m.record(Sample{Offset: 17 * ms, Delay: 40 * ms})
+
+    stats := m.Stats()
+
+    if stats.Count != 3 {
+        t.Errorf("Count = %d, want 3", stats.Count)
+    }
+    if stats.Polls != 5 || stats.Failures != 1 {
+        t.Errorf("Polls, Failures = %d, %d, want 5, 1", stats.Polls, stats.Failures)
+    }
+    if stats.LastErr != nil {
+        t.Errorf("LastErr = %v, want nil", stats.LastErr)
+    }
+    if stats.Latest.Offset != 17*ms {
+        t.Errorf("Latest.Offset = %v, want 17ms", stats.Latest.Offset)
+    }
+    if stats.MeanOffset != 40*ms/3 {
+        t.Errorf("MeanOffset = %v, want %v", stats.MeanOffset, 40*ms/3)
+    }
+    if stats.MinDelay != 10*ms || stats.MaxDelay != 40*ms {
+        t.Errorf("MinDelay, MaxDelay = %v, %v, want 10ms, 40ms", stats.MinDelay, stats.MaxDelay)
+    }
+    // Differences are 3ms and 4ms: sqrt((9+16)/2) ms.
+    if diff := stats.Jitter - 3535534*time.Nanosecond; diff < -time.Microsecond || diff > time.Microsecond {
+        t.Errorf("Jitter = %v, want about 3.5355ms", stats.Jitter)
+    }
+}
+
+func TestMonitorMetrics(t *testing.T) {
+    addr := startTestServer(t, 250*time.Millisecond, nil)
+    m := &Monitor{Options: &Options{Server: addr, Timeout: time.Second}}
+
+    if sample := m.Poll(); sample.Err != nil {
+        t.Fatalf("Poll() unexpected error: %v", sample.Err)
+    }
+
+    rec := httptest.NewRecorder()
+    m.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+    body := rec.Body.String()
+
+    for _, want := range []string{
+        "# TYPE ntp_offset_seconds gauge",
+        "ntp_polls_total{server=\"" + addr + "\"} 1\n",
+        "ntp_offset_seconds{server=\"" + addr + "\"} 0.2",
+        "ntp_jitter_seconds{server=",
+    } {
+        if !strings.Contains(body, want) {
+            t.Errorf("metrics output missing %q:\n%s", want, body)
+        }
+    }
+}
@@ -1,20 +1,59 @@
 package ntp
 
 import (
+    "context"
     "fmt"
     "time"
-
-    "github.com/beevik/ntp"
 )
 
+// DefaultServer is queried when Options.Server is empty.
+const DefaultServer = "pool.ntp.org"
+
+// Options holds settings for NTP queries.
+type Options struct {
+    Server          string
+    Timeout         time.Duration
+    Version         int
+    LocalPort       int
+    TTL             int
+    MaxRootDistance time.Duration
+    MaxRTT          time.Duration
+    Key             *Key
+    LeapSeconds     *LeapTable
+    SmearWindow     time.Duration
+}
+
 //GetCurrentTime returns time by ntp.
-func GetCurrentTime() (time.Time, error) {
-    response, err := ntp.Query("pool.ntp.org")
+// With Options.SmearWindow set, the time is smeared around a leap second.
+func GetCurrentTime(opts *Options) (time.Time, error) {
+    return GetCurrentTimeContext(context.Background(), opts)
+}
+
+// GetCurrentTimeContext is like GetCurrentTime but can be cancelled through ctx.
+func GetCurrentTimeContext(ctx context.Context, opts *Options) (time.Time, error) {
+    currentTime, response, err := queryTime(ctx, opts)
+    if err != nil {
+        return time.Time{}, err
+    }
+
+    return ApplyLeap(currentTime, response.Leap, opts), nil
+}
+
+// queryTime returns the corrected time without leap smearing and the reply it is based on.
+func queryTime(ctx context.Context, opts *Options) (time.Time, *Response, error) {
+    response, err := QueryContext(ctx, serverAddr(opts), opts)
     if err != nil {
-        return time.Time{}, fmt.Errorf("failed to query NTP server: %w", err)
+        return time.Time{}, nil, fmt.Errorf("failed to query NTP server: %w", err)
     }
 
     currentTime := time.Now().Add(response.ClockOffset)
     
-    return currentTime, nil
-}
\ No newline at end of file
+    return currentTime, response, nil
+}
+
+func serverAddr(opts *Options) string {
+    if opts == nil || opts.Server == "" {
+        return DefaultServer
+    }
+    return opts.Server
+}
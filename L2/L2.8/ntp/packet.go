@@ -0,0 +1,131 @@
+package ntp
+
+import (
+    "encoding/binary"
+    "errors"
+    "time"
+)
+
+const (
+    packetSize = 48
+
+    modeClient = 3
+    modeServer = 4
+
+    // ntpEpochOffset is the number of seconds between 1900 and 1970.
+    ntpEpochOffset = 2208988800
+)
+
+// LeapIndicator warns of a leap second inserted or deleted at the end of the current day.
+type LeapIndicator uint8
+
+// Leap indicator values as defined by RFC 4330.
+const (
+    LeapNoWarning LeapIndicator = iota
+    LeapAddSecond
+    LeapDelSecond
+    LeapNotInSync
+)
+
+var errShortPacket = errors.New("NTP packet too short")
+
+// ntpTime is a 64-bit NTP timestamp: seconds since 1900 and a 32-bit fraction.
+type ntpTime uint64
+
+// ntpShort is a 32-bit NTP interval: 16-bit seconds and a 16-bit fraction.
+type ntpShort uint32
+
+// packet is the fixed part of an SNTP message.
+type packet struct {
+    Leap           LeapIndicator
+    Version        uint8
+    Mode           uint8
+    Stratum        uint8
+    Poll           int8
+    Precision      int8
+    RootDelay      ntpShort
+    RootDispersion ntpShort
+    ReferenceID    uint32
+    ReferenceTime  ntpTime
+    OriginTime     ntpTime
+    ReceiveTime    ntpTime
+    TransmitTime   ntpTime
+}
+
+func (p *packet) marshal() []byte {
+    b := make([]byte, packetSize)
+    b[0] = byte(p.Leap)<<6 | (p.Version&0x7)<<3 | p.Mode&0x7
+    b[1] = p.Stratum
+    b[2] = byte(p.Poll)
+    b[3] = byte(p.Precision)
+    binary.BigEndian.PutUint32(b[4:], uint32(p.RootDelay))
+    binary.BigEndian.PutUint32(b[8:], uint32(p.RootDispersion))
+    binary.BigEndian.PutUint32(b[12:], p.ReferenceID)
+    binary.BigEndian.PutUint64(b[16:], uint64(p.ReferenceTime))
+    binary.BigEndian.PutUint64(b[24:], uint64(p.OriginTime))
+    binary.BigEndian.PutUint64(b[32:], uint64(p.ReceiveTime))
+    binary.BigEndian.PutUint64(b[40:], uint64(p.TransmitTime))
+    return b
+}
+
+func (p *packet) unmarshal(b []byte) error {
+    if len(b) < packetSize {
+        return errShortPacket
+    }
+
+    p.Leap = LeapIndicator(b[0] >> 6)
+    p.Version = (b[0] >> 3) & 0x7
+    p.Mode = b[0] & 0x7
+    p.Stratum = b[1]
+    p.Poll = int8(b[2])
+    p.Precision = int8(b[3])
+    p.RootDelay = ntpShort(binary.BigEndian.Uint32(b[4:]))
+    p.RootDispersion = ntpShort(binary.BigEndian.Uint32(b[8:]))
+    p.ReferenceID = binary.BigEndian.Uint32(b[12:])
+    p.ReferenceTime = ntpTime(binary.BigEndian.Uint64(b[16:]))
+    p.OriginTime = ntpTime(binary.BigEndian.Uint64(b[24:]))
+    p.ReceiveTime = ntpTime(binary.BigEndian.Uint64(b[32:]))
+    p.TransmitTime = ntpTime(binary.BigEndian.Uint64(b[40:]))
+    return nil
+}
+
+func toNtpTime(t time.Time) ntpTime {
+    nsec := uint64(t.Sub(time.Unix(-ntpEpochOffset, 0)))
+    sec := nsec / 1e9
+    frac := (nsec % 1e9) << 32 / 1e9
+    return ntpTime(sec<<32 | frac)
+}
+
+// Time converts the timestamp using the era rule of RFC 4330:
+// when the most significant bit is clear the time lies in 2036-2104.
+func (t ntpTime) Time() time.Time {
+    if t == 0 {
+        return time.Time{}
+    }
+
+    sec := int64(t >> 32)
+    if sec&0x80000000 == 0 {
+        sec += 1 << 32
+    }
+    nsec := int64((uint64(t&0xffffffff)*1e9 + 1<<31) >> 32)
+
+    return time.Unix(sec-ntpEpochOffset, nsec)
+}
+
+func toNtpShort(d time.Duration) ntpShort {
+    if d < 0 {
+        return 0
+    }
+    return ntpShort(uint64(d) << 16 / 1e9)
+}
+
+func (s ntpShort) Duration() time.Duration {
+    return time.Duration((uint64(s)*1e9 + 1<<15) >> 16)
+}
+
+func precisionToDuration(p int8) time.Duration {
+    if p >= 0 {
+        return time.Second << uint(p)
+    }
+    return time.Second >> uint(-p)
+}
@@ -0,0 +1,271 @@
+package ntp
+
+import (
+    "bytes"
+    "context"
+    "crypto/ed25519"
+    "crypto/rand"
+    "crypto/sha512"
+    "encoding/binary"
+    "errors"
+    "fmt"
+    "net"
+    "sort"
+    "time"
+)
+
+// Roughtime message tags, the ASCII names read as little-endian integers.
+const (
+    tagSIG  uint32 = 0x00474953
+    tagNONC uint32 = 0x434e4f4e
+    tagPAD  uint32 = 0xff444150
+    tagPATH uint32 = 0x48544150
+    tagSREP uint32 = 0x50455253
+    tagCERT uint32 = 0x54524543
+    tagINDX uint32 = 0x58444e49
+    tagRADI uint32 = 0x49444152
+    tagMIDP uint32 = 0x5044494d
+    tagROOT uint32 = 0x544f4f52
+    tagDELE uint32 = 0x454c4544
+    tagMINT uint32 = 0x544e494d
+    tagMAXT uint32 = 0x5458414d
+    tagPUBK uint32 = 0x4b425550
+)
+
+const (
+    roughtimeNonceSize   = 64
+    roughtimeRequestSize = 1024
+
+    roughtimeDelegationContext = "RoughTime v1 delegation signature--\x00"
+    roughtimeResponseContext   = "RoughTime v1 response signature\x00"
+)
+
+// RoughtimeError reports a Roughtime response that failed verification.
+type RoughtimeError struct {
+    Reason string
+}
+
+func (e *RoughtimeError) Error() string {
+    return "invalid Roughtime response: " + e.Reason
+}
+
+// RoughtimeSource measures the offset with a Roughtime request. The response
+// is signed by a key delegated by PublicKey, so it cannot be forged on path.
+type RoughtimeSource struct {
+    Address   string
+    PublicKey ed25519.PublicKey
+    Timeout   time.Duration
+}
+
+// Name returns "roughtime:" followed by the server address.
+func (s *RoughtimeSource) Name() string {
+    return "roughtime:" + s.Address
+}
+
+// Measure sends a request with a random nonce and verifies the signed reply.
+func (s *RoughtimeSource) Measure(ctx context.Context) (*Reading, error) {
+    if len(s.PublicKey) != ed25519.PublicKeySize {
+        return nil, errors.New("invalid Roughtime public key")
+    }
+
+    nonce := make([]byte, roughtimeNonceSize)
+    if _, err := rand.Read(nonce); err != nil {
+        return nil, err
+    }
+
+    timeout := s.Timeout
+    if timeout <= 0 {
+        timeout = defaultTimeout
+    }
+    ctx, cancel := context.WithTimeout(ctx, timeout)
+    defer cancel()
+
+    var dialer net.Dialer
+    conn, err := dialer.DialContext(ctx, "udp", s.Address)
+    if err != nil {
+        return nil, err
+    }
+    defer conn.Close()
+
+    deadline, _ := ctx.Deadline()
+    if err := conn.SetDeadline(deadline); err != nil {
+        return nil, err
+    }
+    stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+    defer stop()
+
+    sent := time.Now()
+    if _, err := conn.Write(roughtimeRequest(nonce)); err != nil {
+        return nil, contextError(ctx, err)
+    }
+
+    buf := make([]byte, 4096)
+    n, err := conn.Read(buf)
+    if err != nil {
+        return nil, contextError(ctx, err)
+    }
+    rtt := time.Since(sent)
+
+    midpoint, radius, err := verifyRoughtime(buf[:n], nonce, s.PublicKey)
+    if err != nil {
+        return nil, err
+    }
+
+    local := sent.Add(rtt / 2).Round(0)
+    offset := midpoint.Sub(local)
+
+    return &Reading{
+        Source:      s.Name(),
+        Time:        time.Now().Add(offset),
+        Offset:      offset,
+        Uncertainty: radius + rtt/2,
+    }, nil
+}
+
+func roughtimeRequest(nonce []byte) []byte {
+    msg := encodeRoughtime(map[uint32][]byte{tagNONC: nonce, tagPAD: nil})
+    pad := roughtimeRequestSize - len(msg)
+
+    return encodeRoughtime(map[uint32][]byte{tagNONC: nonce, tagPAD: make([]byte, pad)})
+}
+
+// verifyRoughtime checks the delegation and response signatures and the
+// Merkle path of nonce, returning the server midpoint and radius.
+func verifyRoughtime(data, nonce []byte, rootKey ed25519.PublicKey) (time.Time, time.Duration, error) {
+    msg, err := decodeRoughtime(data)
+    if err != nil {
+        return time.Time{}, 0, err
+    }
+
+    cert, err := decodeRoughtime(msg[tagCERT])
+    if err != nil {
+        return time.Time{}, 0, err
+    }
+    dele, err := decodeRoughtime(cert[tagDELE])
+    if err != nil {
+        return time.Time{}, 0, err
+    }
+    if !ed25519.Verify(rootKey, append([]byte(roughtimeDelegationContext), cert[tagDELE]...), cert[tagSIG]) {
+        return time.Time{}, 0, &RoughtimeError{Reason: "bad delegation signature"}
+    }
+
+    delegated := dele[tagPUBK]
+    if len(delegated) != ed25519.PublicKeySize {
+        return time.Time{}, 0, &RoughtimeError{Reason: "bad delegated key"}
+    }
+    if !ed25519.Verify(delegated, append([]byte(roughtimeResponseContext), msg[tagSREP]...), msg[tagSIG]) {
+        return time.Time{}, 0, &RoughtimeError{Reason: "bad response signature"}
+    }
+
+    srep, err := decodeRoughtime(msg[tagSREP])
+    if err != nil {
+        return time.Time{}, 0, err
+    }
+    if len(srep[tagMIDP]) != 8 || len(srep[tagRADI]) != 4 || len(msg[tagINDX]) != 4 ||
+        len(dele[tagMINT]) != 8 || len(dele[tagMAXT]) != 8 {
+        return time.Time{}, 0, &RoughtimeError{Reason: "missing fields"}
+    }
+
+    if !bytes.Equal(roughtimeMerkleRoot(nonce, msg[tagPATH], binary.LittleEndian.Uint32(msg[tagINDX])), srep[tagROOT]) {
+        return time.Time{}, 0, &RoughtimeError{Reason: "nonce not in Merkle tree"}
+    }
+
+    midp := binary.LittleEndian.Uint64(srep[tagMIDP])
+    mint := binary.LittleEndian.Uint64(dele[tagMINT])
+    maxt := binary.LittleEndian.Uint64(dele[tagMAXT])
+    if midp < mint || midp > maxt {
+        return time.Time{}, 0, &RoughtimeError{Reason: "midpoint outside delegation validity"}
+    }
+
+    midpoint := time.UnixMicro(int64(midp))
+    radius := time.Duration(binary.LittleEndian.Uint32(srep[tagRADI])) * time.Microsecond
+
+    return midpoint, radius, nil
+}
+
+func roughtimeMerkleRoot(nonce, path []byte, index uint32) []byte {
+    hash := roughtimeHash(0x00, nonce)
+    for len(path) >= sha512.Size {
+        if index&1 == 0 {
+            hash = roughtimeHash(0x01, hash, path[:sha512.Size])
+        } else {
+            hash = roughtimeHash(0x01, path[:sha512.Size], hash)
+        }
+        index >>= 1
+        path = path[sha512.Size:]
+    }
+    return hash
+}
+
+func roughtimeHash(prefix byte, parts ...[]byte) []byte {
+    h := sha512.New()
+    h.Write([]byte{prefix})
+    for _, p := range parts {
+        h.Write(p)
+    }
+    return h.Sum(nil)
+}
+
+// encodeRoughtime builds a tag-value message with the tags in ascending order.
+// Values are padded to a multiple of four bytes.
+func encodeRoughtime(values map[uint32][]byte) []byte {
+    tags := make([]uint32, 0, len(values))
+    for tag := range values {
+        tags = append(tags, tag)
+    }
+    sort.Slice(tags, func(i, j int) bool { return tags[i] < tags[j] })
+
+    header := binary.LittleEndian.AppendUint32(nil, uint32(len(tags)))
+    var body []byte
+    for i, tag := range tags {
+        if i > 0 {
+            header = binary.LittleEndian.AppendUint32(header, uint32(len(body)))
+        }
+        body = append(body, values[tag]...)
+        for len(body)%4 != 0 {
+            body = append(body, 0)
+        }
+    }
+    for _, tag := range tags {
+        header = binary.LittleEndian.AppendUint32(header, tag)
+    }
+
+    return append(header, body...)
+}
+
+func decodeRoughtime(data []byte) (map[uint32][]byte, error) {
+    if len(data) < 4 {
+        return nil, &RoughtimeError{Reason: "message too short"}
+    }
+
+    n := int(binary.LittleEndian.Uint32(data))
+    headerSize := 4 + 8*n - 4
+    if n == 0 || n > len(data)/8 || len(data) < headerSize {
+        return nil, &RoughtimeError{Reason: fmt.Sprintf("bad tag count %d", n)}
+    }
+
+    body := data[headerSize:]
+    offsets := make([]int, n+1)
+    for i := 1; i < n; i++ {
+        offsets[i] = int(binary.LittleEndian.Uint32(data[4*i:]))
+    }
+    offsets[n] = len(body)
+
+    values := make(map[uint32][]byte, n)
+    var prevTag uint32
+    for i := 0; i < n; i++ {
+        tag := binary.LittleEndian.Uint32(data[4*n+4*i:])
+        if i > 0 && tag <= prevTag {
+            return nil, &RoughtimeError{Reason: "tags not in ascending order"}
+        }
+        prevTag = tag
+
+        start, end := offsets[i], offsets[i+1]
+        if start > end || end > len(body) || start%4 != 0 {
+            return nil, &RoughtimeError{Reason: "bad value offset"}
+        }
+        values[tag] = body[start:end]
+    }
+
+    return values, nil
+}
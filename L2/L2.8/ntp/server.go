@@ -0,0 +1,201 @@
+package ntp
+
+import (
+    "errors"
+    "net"
+    "sync"
+    "time"
+)
+
+const (
+    defaultServerAddr    = ":123"
+    defaultServerStratum = 10
+    defaultRefresh       = 64 * time.Second
+
+    // refIDLocal is "LOCL", the reference ID of an undisciplined local clock.
+    refIDLocal = 0x4c4f434c
+)
+
+// Server answers SNTP client requests using the local clock.
+// When Upstream is set, the clock is corrected by the offset obtained
+// from GetCurrentTime and refreshed every RefreshInterval.
+// When Keys is set, only requests authenticated with one of them are answered.
+type Server struct {
+    Addr            string
+    Stratum         uint8
+    Upstream        *Options
+    RefreshInterval time.Duration
+    Keys            Keys
+
+    mu      sync.Mutex
+    conn    net.PacketConn
+    done    chan struct{}
+    offset  time.Duration
+    refTime time.Time
+    synced  bool
+}
+
+// ListenAndServe listens on s.Addr and serves requests until Close is called.
+func (s *Server) ListenAndServe() error {
+    addr := s.Addr
+    if addr == "" {
+        addr = defaultServerAddr
+    }
+
+    conn, err := net.ListenPacket("udp", addr)
+    if err != nil {
+        return err
+    }
+
+    return s.Serve(conn)
+}
+
+// Serve answers requests received on conn until Close is called.
+// With an upstream configured, the first refresh is done before serving.
+func (s *Server) Serve(conn net.PacketConn) error {
+    s.mu.Lock()
+    s.conn = conn
+    s.done = make(chan struct{})
+    s.mu.Unlock()
+    defer conn.Close()
+
+    if s.Upstream != nil {
+        s.refresh()
+        go s.refreshLoop(s.done)
+    }
+
+    buf := make([]byte, 1024)
+    for {
+        n, addr, err := conn.ReadFrom(buf)
+        if err != nil {
+            if errors.Is(err, net.ErrClosed) {
+                return nil
+            }
+            return err
+        }
+        received := s.now()
+
+        reply, ok := s.reply(buf[:n], received)
+        if !ok {
+            continue
+        }
+        conn.WriteTo(reply, addr)
+    }
+}
+
+// Close stops the server.
+func (s *Server) Close() error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if s.conn == nil {
+        return nil
+    }
+    close(s.done)
+    err := s.conn.Close()
+    s.conn = nil
+    return err
+}
+
+// LocalAddr returns the address the server listens on, or nil before Serve.
+func (s *Server) LocalAddr() net.Addr {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if s.conn == nil {
+        return nil
+    }
+    return s.conn.LocalAddr()
+}
+
+func (s *Server) reply(data []byte, received time.Time) ([]byte, bool) {
+    var request packet
+    if err := request.unmarshal(data); err != nil || request.Mode != modeClient {
+        return nil, false
+    }
+
+    var key *Key
+    if s.Keys != nil {
+        id, ok := macKeyID(data)
+        if key = s.Keys[id]; !ok || key == nil || key.verify(data) != nil {
+            return nil, false
+        }
+    }
+
+    s.mu.Lock()
+    refTime, synced := s.refTime, s.synced
+    s.mu.Unlock()
+
+    if s.Upstream == nil {
+        refTime, synced = received, true
+    }
+
+    stratum := s.Stratum
+    if stratum == 0 {
+        stratum = defaultServerStratum
+    }
+
+    reply := &packet{
+        Leap:          LeapNoWarning,
+        Version:       request.Version,
+        Mode:          modeServer,
+        Stratum:       stratum,
+        Poll:          request.Poll,
+        Precision:     -20,
+        ReferenceID:   refIDLocal,
+        ReferenceTime: toNtpTime(refTime),
+        OriginTime:    request.TransmitTime,
+        ReceiveTime:   toNtpTime(received),
+    }
+    if !synced {
+        reply.Leap = LeapNotInSync
+    }
+    reply.TransmitTime = toNtpTime(s.now())
+
+    msg := reply.marshal()
+    if key != nil {
+        msg = key.sign(msg)
+    }
+
+    return msg, true
+}
+
+func (s *Server) now() time.Time {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    return time.Now().Add(s.offset)
+}
+
+func (s *Server) refreshLoop(done <-chan struct{}) {
+    interval := s.RefreshInterval
+    if interval <= 0 {
+        interval = defaultRefresh
+    }
+
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-done:
+            return
+        case <-ticker.C:
+            s.refresh()
+        }
+    }
+}
+
+func (s *Server) refresh() {
+    upstream, err := GetCurrentTime(s.Upstream)
+    if err != nil {
+        return
+    }
+    offset := upstream.Sub(time.Now())
+
+    s.mu.Lock()
+    s.offset = offset
+    s.refTime = upstream
+    s.synced = true
+    s.mu.Unlock()
+}
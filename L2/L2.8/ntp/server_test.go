@@ -0,0 +1,71 @@
+package ntp
+
+import (
+    "net"
+    "testing"
+    "time"
+)
+
+func startServer(t *testing.T, s *Server) string {
+    t.Helper()
+
+    conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatalf("listen: %v", err)
+    }
+
+    go s.Serve(conn)
+    t.Cleanup(func() { s.Close() })
+
+    return conn.LocalAddr().String()
+}
+
+func TestServerLocalClock(t *testing.T) {
+    addr := startServer(t, &Server{Stratum: 5})
+
+    response, err := Query(addr, &Options{Timeout: time.Second})
+    if err != nil {
+        t.Fatalf("Query() unexpected error: %v", err)
+    }
+
+    if response.Stratum != 5 {
+        t.Errorf("Stratum = %d, want 5", response.Stratum)
+    }
+    if response.Leap != LeapNoWarning {
+        t.Errorf("Leap = %d, want %d", response.Leap, LeapNoWarning)
+    }
+    if response.ClockOffset < -50*time.Millisecond || response.ClockOffset > 50*time.Millisecond {
+        t.Errorf("ClockOffset = %v, want about 0", response.ClockOffset)
+    }
+}
+
+func TestServerUpstream(t *testing.T) {
+    upstream := startTestServer(t, 2*time.Second, nil)
+    addr := startServer(t, &Server{Upstream: &Options{Server: upstream, Timeout: time.Second}})
+
+    var response *Response
+    var err error
+    for i := 0; i < 20; i++ {
+        response, err = Query(addr, &Options{Timeout: time.Second})
+        if err == nil && response.Leap != LeapNotInSync {
+            break
+        }
+        time.Sleep(10 * time.Millisecond)
+    }
+    if err != nil {
+        t.Fatalf("Query() unexpected error: %v", err)
+    }
+
+    if diff := response.ClockOffset - 2*time.Second; diff < -50*time.Millisecond || diff > 50*time.Millisecond {
+        t.Errorf("ClockOffset = %v, want about 2s", response.ClockOffset)
+    }
+}
+
+func TestServerIgnoresNonClientModes(t *testing.T) {
+    s := &Server{}
+    request := &packet{Version: 4, Mode: modeServer}
+
+    if _, ok := s.reply(request.marshal(), time.Now()); ok {
+        t.Error("reply() answered a server-mode packet")
+    }
+}
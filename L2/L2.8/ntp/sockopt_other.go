@@ -0,0 +1,19 @@
+//go:build !unix
+
+package ntp
+
+import (
+    "errors"
+    "syscall"
+)
+
+// socketControl reports that TTL is not supported on this platform.
+func socketControl(ttl int) func(network, address string, c syscall.RawConn) error {
+    if ttl <= 0 {
+        return nil
+    }
+
+    return func(network, address string, c syscall.RawConn) error {
+        return errors.New("setting TTL is not supported on this platform")
+    }
+}
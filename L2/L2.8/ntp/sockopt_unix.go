@@ -0,0 +1,29 @@
+//go:build unix
+
+package ntp
+
+import (
+    "syscall"
+)
+
+// socketControl sets the IP time-to-live of the query socket.
+func socketControl(ttl int) func(network, address string, c syscall.RawConn) error {
+    if ttl <= 0 {
+        return nil
+    }
+
+    return func(network, address string, c syscall.RawConn) error {
+        var sockErr error
+        err := c.Control(func(fd uintptr) {
+            if network == "udp6" {
+                sockErr = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IPV6, syscall.IPV6_UNICAST_HOPS, ttl)
+            } else {
+                sockErr = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IP, syscall.IP_TTL, ttl)
+            }
+        })
+        if err != nil {
+            return err
+        }
+        return sockErr
+    }
+}
@@ -0,0 +1,82 @@
+package ntp
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "time"
+)
+
+// Reading is the offset of the local clock reported by a TimeSource.
+// The true offset lies within Offset ± Uncertainty. Leap is set by
+// sources that announce leap seconds.
+type Reading struct {
+    Source      string
+    Time        time.Time
+    Offset      time.Duration
+    Uncertainty time.Duration
+    Leap        LeapIndicator
+}
+
+// TimeSource measures the offset of the local clock against a remote clock.
+type TimeSource interface {
+    Name() string
+    Measure(ctx context.Context) (*Reading, error)
+}
+
+// NTPSource measures the offset with an SNTP query.
+type NTPSource struct {
+    Options *Options
+}
+
+// Name returns "ntp:" followed by the server address.
+func (s *NTPSource) Name() string {
+    return "ntp:" + serverAddr(s.Options)
+}
+
+// Measure queries the server; the uncertainty is its root distance.
+func (s *NTPSource) Measure(ctx context.Context) (*Reading, error) {
+    response, err := QueryContext(ctx, serverAddr(s.Options), s.Options)
+    if err != nil {
+        return nil, err
+    }
+
+    return &Reading{
+        Source:      s.Name(),
+        Time:        time.Now().Add(response.ClockOffset),
+        Offset:      response.ClockOffset,
+        Uncertainty: response.RootDistance,
+        Leap:        response.Leap,
+    }, nil
+}
+
+// Chain is a TimeSource that tries its sources in order and returns the
+// first successful reading.
+type Chain []TimeSource
+
+// Name returns "chain".
+func (c Chain) Name() string {
+    return "chain"
+}
+
+// Measure returns the reading of the first source that succeeds, or all errors joined.
+func (c Chain) Measure(ctx context.Context) (*Reading, error) {
+    if len(c) == 0 {
+        return nil, errors.New("empty time source chain")
+    }
+
+    var errs []error
+    for _, source := range c {
+        reading, err := source.Measure(ctx)
+        if err == nil {
+            return reading, nil
+        }
+        errs = append(errs, fmt.Errorf("%s: %w", source.Name(), err))
+
+        if ctx.Err() != nil {
+            break
+        }
+    }
+
+    return nil, errors.Join(errs...)
+}
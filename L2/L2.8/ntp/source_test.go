@@ -0,0 +1,160 @@
+package ntp
+
+import (
+    "context"
+    "crypto/ed25519"
+    "encoding/binary"
+    "errors"
+    "net"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+    "time"
+)
+
+// startRoughtimeServer answers Roughtime requests with a clock shifted by
+// offset, signing with a key delegated by rootKey.
+func startRoughtimeServer(t *testing.T, rootKey ed25519.PrivateKey, offset time.Duration) string {
+    t.Helper()
+
+    conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatalf("listen: %v", err)
+    }
+    t.Cleanup(func() { conn.Close() })
+
+    delegatedPub, delegatedKey, err := ed25519.GenerateKey(nil)
+    if err != nil {
+        t.Fatalf("generate key: %v", err)
+    }
+    now := uint64(time.Now().UnixMicro())
+    dele := encodeRoughtime(map[uint32][]byte{
+        tagMINT: binary.LittleEndian.AppendUint64(nil, now-uint64(time.Hour/time.Microsecond)),
+        tagMAXT: binary.LittleEndian.AppendUint64(nil, now+uint64(2*time.Hour/time.Microsecond)),
+        tagPUBK: delegatedPub,
+    })
+    cert := encodeRoughtime(map[uint32][]byte{
+        tagDELE: dele,
+        tagSIG:  ed25519.Sign(rootKey, append([]byte(roughtimeDelegationContext), dele...)),
+    })
+
+    go func() {
+        buf := make([]byte, 2048)
+        for {
+            n, addr, err := conn.ReadFrom(buf)
+            if err != nil {
+                return
+            }
+            request, err := decodeRoughtime(buf[:n])
+            if err != nil || len(request[tagNONC]) != roughtimeNonceSize {
+                continue
+            }
+
+            midpoint := time.Now().Add(offset).UnixMicro()
+            srep := encodeRoughtime(map[uint32][]byte{
+                tagRADI: binary.LittleEndian.AppendUint32(nil, 1000),
+                tagMIDP: binary.LittleEndian.AppendUint64(nil, uint64(midpoint)),
+                tagROOT: roughtimeMerkleRoot(request[tagNONC], nil, 0),
+            })
+            reply := encodeRoughtime(map[uint32][]byte{
+                tagSIG:  ed25519.Sign(delegatedKey, append([]byte(roughtimeResponseContext), srep...)),
+                tagPATH: nil,
+                tagSREP: srep,
+                tagCERT: cert,
+                tagINDX: make([]byte, 4),
+            })
+            conn.WriteTo(reply, addr)
+        }
+    }()
+
+    return conn.LocalAddr().String()
+}
+
+func TestRoughtimeSource(t *testing.T) {
+    rootPub, rootKey, err := ed25519.GenerateKey(nil)
+    if err != nil {
+        t.Fatalf("generate key: %v", err)
+    }
+    addr := startRoughtimeServer(t, rootKey, 10*time.Second)
+
+    source := &RoughtimeSource{Address: addr, PublicKey: rootPub, Timeout: time.Second}
+    reading, err := source.Measure(context.Background())
+    if err != nil {
+        t.Fatalf("Measure() unexpected error: %v", err)
+    }
+    if diff := reading.Offset - 10*time.Second; diff < -50*time.Millisecond || diff > 50*time.Millisecond {
+        t.Errorf("Offset = %v, want about 10s", reading.Offset)
+    }
+    if reading.Uncertainty < time.Millisecond {
+        t.Errorf("Uncertainty = %v, want at least the 1ms radius", reading.Uncertainty)
+    }
+
+    otherPub, _, _ := ed25519.GenerateKey(nil)
+    source.PublicKey = otherPub
+    var rtErr *RoughtimeError
+    if _, err := source.Measure(context.Background()); !errors.As(err, &rtErr) {
+        t.Errorf("Measure() with wrong key error = %v, want *RoughtimeError", err)
+    }
+}
+
+func TestRoughtimeMessageRoundTrip(t *testing.T) {
+    values := map[uint32][]byte{
+        tagNONC: make([]byte, roughtimeNonceSize),
+        tagRADI: {1, 2, 3, 4},
+        tagPATH: nil,
+    }
+
+    decoded, err := decodeRoughtime(encodeRoughtime(values))
+    if err != nil {
+        t.Fatalf("decodeRoughtime() unexpected error: %v", err)
+    }
+    for tag, want := range values {
+        if got := decoded[tag]; string(got) != string(want) {
+            t.Errorf("tag %#x = %v, want %v", tag, got, want)
+        }
+    }
+
+    if n := len(roughtimeRequest(make([]byte, roughtimeNonceSize))); n != roughtimeRequestSize {
+        t.Errorf("request size = %d, want %d", n, roughtimeRequestSize)
+    }
+}
+
+func TestHTTPDateSource(t *testing.T) {
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Header().Set("Date", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+    }))
+    defer server.Close()
+
+    reading, err := (&HTTPDateSource{URL: server.URL}).Measure(context.Background())
+    if err != nil {
+        t.Fatalf("Measure() unexpected error: %v", err)
+    }
+    if diff := reading.Offset - time.Hour; diff < -time.Second || diff > time.Second {
+        t.Errorf("Offset = %v, want about 1h", reading.Offset)
+    }
+    if reading.Uncertainty < 500*time.Millisecond {
+        t.Errorf("Uncertainty = %v, want at least 500ms", reading.Uncertainty)
+    }
+}
+
+func TestChain(t *testing.T) {
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+    defer server.Close()
+
+    chain := Chain{
+        &NTPSource{Options: &Options{Server: "127.0.0.1:1", Timeout: 100 * time.Millisecond}},
+        &HTTPDateSource{URL: server.URL},
+    }
+
+    reading, err := chain.Measure(context.Background())
+    if err != nil {
+        t.Fatalf("Measure() unexpected error: %v", err)
+    }
+    if reading.Source != "http:"+server.URL {
+        t.Errorf("Source = %q, want the HTTP source", reading.Source)
+    }
+
+    if _, err := chain[:1].Measure(context.Background()); err == nil {
+        t.Error("Measure() of failing chain expected error, got none")
+    }
+}
@@ -0,0 +1,175 @@
+package ntp
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net"
+    "os"
+    "path/filepath"
+    "time"
+)
+
+const (
+    // minDriftInterval is the shortest span over which the drift rate is measured.
+    minDriftInterval = 10 * time.Minute
+
+    // driftTolerance is the assumed frequency error of an undisciplined clock (15 PPM, RFC 5905).
+    driftTolerance = 15e-6
+)
+
+// State is the last good measurement and the estimated drift rate of the
+// local clock, persisted between runs to estimate the time offline.
+type State struct {
+    Time        time.Time     `json:"time"`
+    Offset      time.Duration `json:"offset"`
+    Distance    time.Duration `json:"distance"`
+    Drift       float64       `json:"drift"`
+    DriftTime   time.Time     `json:"drift_time"`
+    DriftOffset time.Duration `json:"drift_offset"`
+}
+
+// Estimate is a corrected time with a bound on its error.
+// Estimated is set when the time was derived from a saved State.
+type Estimate struct {
+    Time        time.Time
+    Offset      time.Duration
+    Uncertainty time.Duration
+    Estimated   bool
+}
+
+// LoadState reads a state file. A missing file yields an empty State.
+func LoadState(path string) (*State, error) {
+    data, err := os.ReadFile(path)
+    if errors.Is(err, os.ErrNotExist) {
+        return &State{}, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    var s State
+    if err := json.Unmarshal(data, &s); err != nil {
+        return nil, fmt.Errorf("invalid state file %s: %w", path, err)
+    }
+    return &s, nil
+}
+
+// Save writes the state to path, replacing the file atomically.
+func (s *State) Save(path string) error {
+    data, err := json.MarshalIndent(s, "", "  ")
+    if err != nil {
+        return err
+    }
+
+    tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+    if err != nil {
+        return err
+    }
+    defer os.Remove(tmp.Name())
+
+    if _, err := tmp.Write(data); err != nil {
+        tmp.Close()
+        return err
+    }
+    if err := tmp.Close(); err != nil {
+        return err
+    }
+
+    return os.Rename(tmp.Name(), path)
+}
+
+// Record stores a measurement taken at local time at. The drift rate is
+// updated once minDriftInterval has passed since the previous drift anchor.
+func (s *State) Record(at time.Time, offset, distance time.Duration) {
+    at = at.Round(0)
+
+    if s.DriftTime.IsZero() || at.Before(s.DriftTime) {
+        s.DriftTime, s.DriftOffset = at, offset
+    } else if elapsed := at.Sub(s.DriftTime); elapsed >= minDriftInterval {
+        s.Drift = float64(offset-s.DriftOffset) / float64(elapsed)
+        s.DriftTime, s.DriftOffset = at, offset
+    }
+
+    s.Time, s.Offset, s.Distance = at, offset, distance
+}
+
+// Estimate extrapolates the saved offset to local time at. The uncertainty
+// grows from the measured root distance by driftTolerance per elapsed second.
+func (s *State) Estimate(at time.Time) Estimate {
+    at = at.Round(0)
+    elapsed := at.Sub(s.Time)
+    offset := s.Offset + time.Duration(s.Drift*float64(elapsed))
+
+    if elapsed < 0 {
+        elapsed = -elapsed
+    }
+    uncertainty := s.Distance + time.Duration(driftTolerance*float64(elapsed))
+
+    return Estimate{
+        Time:        at.Add(offset),
+        Offset:      offset,
+        Uncertainty: uncertainty,
+        Estimated:   true,
+    }
+}
+
+// GetCurrentTimeOrEstimate queries the server and records the result in the
+// state file. When the server cannot be reached and the file holds a
+// measurement, the time is estimated from it instead.
+func GetCurrentTimeOrEstimate(ctx context.Context, opts *Options, statePath string) (*Estimate, error) {
+    response, err := QueryContext(ctx, serverAddr(opts), opts)
+    if err != nil {
+        if ctx.Err() != nil {
+            return nil, err
+        }
+        return RecordOrEstimate(statePath, 0, 0, fmt.Errorf("failed to query NTP server: %w", err))
+    }
+    return RecordOrEstimate(statePath, response.ClockOffset, response.RootDistance, nil)
+}
+
+// RecordOrEstimate records in the state file at path the offset and root
+// distance just measured, or handles err if the measurement failed. Only
+// when the server could not be reached and the file holds a measurement is
+// the time estimated from it; other errors, such as a failed authentication,
+// are returned as they are. Timeouts count as unreachable, so callers must
+// return the errors of their own context before calling it.
+func RecordOrEstimate(path string, offset, distance time.Duration, err error) (*Estimate, error) {
+    if err != nil && !unreachable(err) {
+        return nil, err
+    }
+
+    state, loadErr := LoadState(path)
+    if loadErr != nil {
+        return nil, loadErr
+    }
+
+    now := time.Now()
+    if err != nil {
+        if state.Time.IsZero() {
+            return nil, err
+        }
+        estimate := state.Estimate(now)
+        return &estimate, nil
+    }
+
+    state.Record(now, offset, distance)
+    if err := state.Save(path); err != nil {
+        return nil, err
+    }
+
+    return &Estimate{
+        Time:        now.Add(offset),
+        Offset:      offset,
+        Uncertainty: distance,
+    }, nil
+}
+
+// unreachable reports whether err is a network error or timeout, as opposed
+// to a reply that was rejected. A lookup timing out under the query timeout
+// also matches context.DeadlineExceeded, so that is not checked here.
+func unreachable(err error) bool {
+    var netErr net.Error
+    return errors.As(err, &netErr)
+}
@@ -0,0 +1,140 @@
+package ntp
+
+import (
+    "context"
+    "errors"
+    "path/filepath"
+    "testing"
+    "time"
+)
+
+func TestStateDriftEstimate(t *testing.T) {
+    start := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+    s := &State{}
+
+    s.Record(start, 10*time.Millisecond, time.Millisecond)
+    s.Record(start.Add(time.Minute), 11*time.Millisecond, time.Millisecond)
+    if s.Drift != 0 {
+        t.Errorf("Drift = %v before minDriftInterval, want 0", s.Drift)
+    }
+
+    // 20ms gained over 1000s is a drift of 20 PPM.
+    s.Record(start.Add(1000*time.Second), 30*time.Millisecond, 2*time.Millisecond)
+    if s.Drift < 19.9e-6 || s.Drift > 20.1e-6 {
+        t.Errorf("Drift = %v, want 2e-5", s.Drift)
+    }
+
+    at := start.Add(2000 * time.Second)
+    e := s.Estimate(at)
+    if !e.Estimated {
+        t.Error("Estimated = false, want true")
+    }
+    if e.Offset != 50*time.Millisecond {
+        t.Errorf("Offset = %v, want 50ms", e.Offset)
+    }
+    if e.Uncertainty != 2*time.Millisecond+15*time.Millisecond {
+        t.Errorf("Uncertainty = %v, want 17ms", e.Uncertainty)
+    }
+    if !e.Time.Equal(at.Add(50 * time.Millisecond)) {
+        t.Errorf("Time = %v, want %v", e.Time, at.Add(50*time.Millisecond))
+    }
+}
+
+func TestStateSaveLoad(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "ntp.state")
+
+    empty, err := LoadState(path)
+    if err != nil || !empty.Time.IsZero() {
+        t.Fatalf("LoadState() of missing file = %+v, %v", empty, err)
+    }
+
+    s := &State{}
+    s.Record(time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC), 5*time.Millisecond, time.Millisecond)
+    if err := s.Save(path); err != nil {
+        t.Fatalf("Save() unexpected error: %v", err)
+    }
+
+    loaded, err := LoadState(path)
+    if err != nil {
+        t.Fatalf("LoadState() unexpected error: %v", err)
+    }
+    if !loaded.Time.Equal(s.Time) || loaded.Offset != s.Offset || loaded.Distance != s.Distance {
+        t.Errorf("LoadState() = %+v, want %+v", loaded, s)
+    }
+}
+
+func TestGetCurrentTimeOrEstimate(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "ntp.state")
+    addr := startTestServer(t, time.Minute, nil)
+
+    measured, err := GetCurrentTimeOrEstimate(context.Background(), &Options{Server: addr, Timeout: time.Second}, path)
+    if err != nil {
+        t.Fatalf("GetCurrentTimeOrEstimate() unexpected error: %v", err)
+    }
+    if measured.Estimated {
+        t.Error("Estimated = true for a measured time")
+    }
+
+    offline := &Options{Server: "127.0.0.1:1", Timeout: 100 * time.Millisecond}
+    estimate, err := GetCurrentTimeOrEstimate(context.Background(), offline, path)
+    if err != nil {
+        t.Fatalf("GetCurrentTimeOrEstimate() offline unexpected error: %v", err)
+    }
+    if !estimate.Estimated {
+        t.Error("Estimated = false for an offline estimate")
+    }
+    if diff := estimate.Offset - time.Minute; diff < -50*time.Millisecond || diff > 50*time.Millisecond {
+        t.Errorf("Offset = %v, want about 1m", estimate.Offset)
+    }
+
+    if _, err := GetCurrentTimeOrEstimate(context.Background(), offline, filepath.Join(t.TempDir(), "none")); err == nil {
+        t.Error("GetCurrentTimeOrEstimate() without state expected error, got none")
+    }
+}
+
+func TestGetCurrentTimeOrEstimateLookupTimeout(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "ntp.state")
+    addr := startTestServer(t, time.Minute, nil)
+    if _, err := GetCurrentTimeOrEstimate(context.Background(), &Options{Server: addr, Timeout: time.Second}, path); err != nil {
+        t.Fatalf("GetCurrentTimeOrEstimate() unexpected error: %v", err)
+    }
+
+    silentResolver(t)
+    offline := &Options{Server: "ntp.example.com", Timeout: 200 * time.Millisecond}
+    estimate, err := GetCurrentTimeOrEstimate(context.Background(), offline, path)
+    if err != nil {
+        t.Fatalf("GetCurrentTimeOrEstimate() with lookup timeout unexpected error: %v", err)
+    }
+    if !estimate.Estimated {
+        t.Error("Estimated = false after a lookup timeout")
+    }
+}
+
+func TestGetCurrentTimeOrEstimateNoFallback(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "ntp.state")
+    good := startTestServer(t, time.Minute, nil)
+    if _, err := GetCurrentTimeOrEstimate(context.Background(), &Options{Server: good, Timeout: time.Second}, path); err != nil {
+        t.Fatalf("GetCurrentTimeOrEstimate() unexpected error: %v", err)
+    }
+
+    deny := startTestServer(t, 0, func(p *packet) {
+        p.Stratum = 0
+        p.ReferenceID = 0x44454e59 // DENY
+    })
+    var kod *KissOfDeathError
+    if _, err := GetCurrentTimeOrEstimate(context.Background(), &Options{Server: deny, Timeout: time.Second}, path); !errors.As(err, &kod) {
+        t.Errorf("GetCurrentTimeOrEstimate() error = %v, want *KissOfDeathError", err)
+    }
+
+    bad := &Options{Server: good, Timeout: time.Second, Key: &Key{ID: 1, Type: "none"}}
+    var authErr *AuthError
+    if _, err := GetCurrentTimeOrEstimate(context.Background(), bad, path); !errors.As(err, &authErr) {
+        t.Errorf("GetCurrentTimeOrEstimate() error = %v, want *AuthError", err)
+    }
+
+    ctx, cancel := context.WithCancel(context.Background())
+    cancel()
+    if _, err := GetCurrentTimeOrEstimate(ctx, &Options{Server: good, Timeout: time.Second}, path); err != context.Canceled {
+        t.Errorf("GetCurrentTimeOrEstimate() error = %v, want %v", err, context.Canceled)
+    }
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+    "flag"
+    "log"
+    "os"
+    "os/signal"
+    "syscall"
+    "time"
+
+    "ntp/ntp"
+)
+
+func runServe(args []string) {
+    var (
+        listen     string
+        stratum    int
+        refresh    time.Duration
+        serverKeys string
+    )
+
+    fs := flag.NewFlagSet("serve", flag.ExitOnError)
+    fs.StringVar(&listen, "listen", ":123", "UDP address to serve on")
+    fs.IntVar(&stratum, "stratum", 10, "stratum reported to clients")
+    fs.DurationVar(&refresh, "refresh", 64*time.Second, "upstream refresh interval")
+    fs.StringVar(&serverKeys, "server-keys", "", "ntp.keys file; when set only authenticated requests are answered")
+    client := newClientFlags(fs, "upstream", "", "upstream NTP server disciplining the clock")
+    fs.Parse(args)
+
+    upstream, err := client.options()
+    if err != nil {
+        log.Fatalf("Error: %v", err)
+    }
+
+    server := &ntp.Server{
+        Addr:            listen,
+        Stratum:         uint8(stratum),
+        RefreshInterval: refresh,
+    }
+    if upstream.Server != "" {
+        server.Upstream = upstream
+    }
+    if serverKeys != "" {
+        server.Keys, err = ntp.LoadKeys(serverKeys)
+        if err != nil {
+            log.Fatalf("Error: %v", err)
+        }
+    }
+
+    signalCh := make(chan os.Signal, 1)
+    signal.Notify(signalCh, os.Interrupt, syscall.SIGTERM)
+    go func() {
+        <-signalCh
+        server.Close()
+    }()
+
+    log.Printf("Serving NTP on %s", listen)
+    if err := server.ListenAndServe(); err != nil {
+        log.Fatalf("Error: %v", err)
+    }
+}
@@ -1,27 +1,114 @@
 package main
 
 import (
+    "bufio"
+    "flag"
     "fmt"
+    "io"
+    "os"
+    "strings"
+
     "unpack/unpack"
 )
 
 func main() {
-    testCases := []string{
-        "a4bc2d5e",
-        "abcd", 
-        "45",
-        "",
-        "qwe\\4\\5",
-        "qwe\\45",
-        "a1b2c3",
-    }
-
-    for _, test := range testCases {
-        result, err := unpack.Unpack(test)
+    var decode, encode, graphemes bool
+    flag.BoolVar(&decode, "d", false, "unpack each line (default)")
+    flag.BoolVar(&encode, "e", false, "pack each line")
+    flag.BoolVar(&graphemes, "g", false, "repeat grapheme clusters instead of runes")
+    flag.Usage = func() {
+        fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [-d | -e] [-g] [file ...]\n", os.Args[0])
+        flag.PrintDefaults()
+    }
+    flag.Parse()
+
+    if decode && encode {
+        fmt.Fprintln(os.Stderr, "Error: -d and -e are mutually exclusive")
+        os.Exit(2)
+    }
+
+    opts := &unpack.Options{Graphemes: graphemes}
+    convert := func(s string) (string, error) {
+        return unpack.UnpackWithOptions(s, opts)
+    }
+    if encode {
+        convert = func(s string) (string, error) {
+            return unpack.PackWithOptions(s, opts), nil
+        }
+    }
+
+    files := flag.Args()
+    if len(files) == 0 {
+        files = []string{"-"}
+    }
+
+    out := bufio.NewWriter(os.Stdout)
+    failed := false
+    for _, name := range files {
+        if !processFile(name, convert, out) {
+            failed = true
+        }
+    }
+    if err := out.Flush(); err != nil {
+        fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+        os.Exit(1)
+    }
+    if failed {
+        os.Exit(1)
+    }
+}
+
+// processFile converts the named file, or stdin for "-", reporting any
+// errors to stderr. It returns false if anything failed.
+func processFile(name string, convert func(string) (string, error), out io.Writer) bool {
+    r := io.Reader(os.Stdin)
+    if name == "-" {
+        name = "stdin"
+    } else {
+        f, err := os.Open(name)
         if err != nil {
-            fmt.Printf("Input: %q -> Error: %v\n", test, err)
+            fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+            return false
+        }
+        defer f.Close()
+        r = f
+    }
+
+    failed, err := process(r, name, convert, out, os.Stderr)
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "Error: %s: %v\n", name, err)
+        return false
+    }
+    return failed == 0
+}
+
+// process converts r line by line, writing the results to out and the
+// errors, prefixed with name and the line number, to errOut. Lines that
+// fail produce no output. It returns the number of failed lines.
+func process(r io.Reader, name string, convert func(string) (string, error), out, errOut io.Writer) (int, error) {
+    in := bufio.NewReader(r)
+    failed := 0
+
+    for n := 1; ; n++ {
+        line, err := in.ReadString('\n')
+        if err != nil && err != io.EOF {
+            return failed, err
+        }
+        if line == "" && err == io.EOF {
+            return failed, nil
+        }
+
+        line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+        result, convErr := convert(line)
+        if convErr != nil {
+            fmt.Fprintf(errOut, "%s:%d: %v\n", name, n, convErr)
+            failed++
         } else {
-            fmt.Printf("Input: %q -> Output: %q\n", test, result)
+            fmt.Fprintln(out, result)
+        }
+
+        if err == io.EOF {
+            return failed, nil
         }
     }
-}
\ No newline at end of file
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+    "bytes"
+    "strings"
+    "testing"
+
+    "unpack/unpack"
+)
+
+func TestProcess(t *testing.T) {
+    tests := []struct {
+        name     string
+        input    string
+        expected string
+        errors   string
+        failed   int
+    }{
+        {
+            name:     "lines",
+            input:    "a4bc2d5e\nabcd\n",
+            expected: "aaaabccddddde\nabcd\n",
+        },
+        {
+            name:     "no trailing newline",
+            input:    "a2\r\nb3",
+            expected: "aa\nbbb\n",
+        },
+        {
+            name:     "empty line",
+            input:    "\n",
+            expected: "\n",
+        },
+        {
+            name:     "errors",
+            input:    "a2\n45\nb\na0\n",
+            expected: "aa\nb\n",
+            errors: "test:2: invalid string: count without a preceding rune: '4' at offset 0\n" +
+                "test:4: invalid string: zero count: '0' at offset 1\n",
+            failed: 2,
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            var out, errOut bytes.Buffer
+            failed, err := process(strings.NewReader(tt.input), "test", unpack.Unpack, &out, &errOut)
+            if err != nil {
+                t.Fatalf("process() unexpected error: %v", err)
+            }
+            if failed != tt.failed {
+                t.Errorf("process() failed = %d, want %d", failed, tt.failed)
+            }
+            if out.String() != tt.expected {
+                t.Errorf("process() output = %q, want %q", out.String(), tt.expected)
+            }
+            if errOut.String() != tt.errors {
+                t.Errorf("process() errors = %q, want %q", errOut.String(), tt.errors)
+            }
+        })
+    }
+}
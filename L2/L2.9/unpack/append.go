@@ -0,0 +1,105 @@
+package unpack
+
+import (
+    "slices"
+    "unicode"
+    "unicode/utf8"
+)
+
+// AppendUnpack appends the unpacked form of src to dst and returns the
+// extended buffer. It computes the output size in a validation pass and
+// writes UTF-8 directly, so it does not allocate when dst has enough
+// capacity. The output is bounded by DefaultMaxOutputRunes, as for Unpack.
+// On error dst is returned unchanged.
+func AppendUnpack(dst []byte, src string) ([]byte, error) {
+    _, size, _, ok := appendSize(src, 0, 0)
+    if !ok {
+        // Take the slow path for the precise error.
+        _, err := Unpack(src)
+        return dst, err
+    }
+
+    dst = slices.Grow(dst, size)
+    dst, _ = appendRuns(dst, src, 0)
+    return dst, nil
+}
+
+// appendSize returns the unpacked length in runes and in bytes of src from
+// pos up to the end or, inside a group, up to its closing parenthesis, and
+// the position after that. ok is false if src is invalid or too large.
+func appendSize(src string, pos, depth int) (runes, size, end int, ok bool) {
+    for pos < len(src) {
+        n, m := 1, 0
+        switch c := src[pos]; c {
+        case '\\':
+            if pos+1 == len(src) {
+                return 0, 0, 0, false
+            }
+            unit, w := nextUnit(src[pos+1:], false)
+            m, pos = len(unit), pos+1+w
+        case '(':
+            if depth == maxDepth || pos+1 < len(src) && src[pos+1] == ')' {
+                return 0, 0, 0, false
+            }
+            if n, m, pos, ok = appendSize(src, pos+1, depth+1); !ok {
+                return 0, 0, 0, false
+            }
+        case ')':
+            return runes, size, pos + 1, depth > 0
+        default:
+            if r, _ := utf8.DecodeRuneInString(src[pos:]); unicode.IsDigit(r) {
+                return 0, 0, 0, false
+            }
+            unit, w := nextUnit(src[pos:], false)
+            m, pos = len(unit), pos+w
+        }
+
+        // Bounding runes bounds bytes too, as a rune is at most 4 bytes.
+        count, w, err := extractNumber(src[pos:], pos, Dialect{})
+        if err != nil || count > (DefaultMaxOutputRunes-runes)/n {
+            return 0, 0, 0, false
+        }
+        pos += w
+        runes += n * count
+        size += m * count
+    }
+
+    return runes, size, pos, depth == 0
+}
+
+// appendRuns appends the unpacked form of src, which appendSize has
+// validated, from pos up to the end or the closing parenthesis of a group.
+func appendRuns(dst []byte, src string, pos int) ([]byte, int) {
+    for pos < len(src) {
+        start := len(dst)
+        switch src[pos] {
+        case '\\':
+            unit, w := nextUnit(src[pos+1:], false)
+            dst, pos = append(dst, unit...), pos+1+w
+        case '(':
+            dst, pos = appendRuns(dst, src, pos+1)
+        case ')':
+            return dst, pos + 1
+        default:
+            unit, w := nextUnit(src[pos:], false)
+            dst, pos = append(dst, unit...), pos+w
+        }
+
+        count, w, _ := extractNumber(src[pos:], pos, Dialect{})
+        pos += w
+        dst = appendRepeat(dst, start, count)
+    }
+    return dst, pos
+}
+
+// appendRepeat extends dst so that dst[start:] occurs count times, doubling
+// the copied span each step. dst must have the capacity for it.
+func appendRepeat(dst []byte, start, count int) []byte {
+    n := len(dst) - start
+    end := start + n*count
+    dst = dst[:end]
+    for filled := n; filled < n*count; filled *= 2 {
+        copy(dst[start+filled:end], dst[start:start+filled])
+    }
+    return dst
+}
@@ -0,0 +1,122 @@
+package unpack
+
+import (
+    "errors"
+    "reflect"
+    "strings"
+    "testing"
+    "unicode/utf8"
+)
+
+func TestAppendUnpack(t *testing.T) {
+    inputs := []string{
+        "a4bc2d5e",
+        "abcd",
+        "",
+        "qwe\\4\\5",
+        "qwe\\45",
+        "qwe\\\\5",
+        "é3日",
+        "(ab2)3c",
+        "x((ab)2\\)c)2y",
+        "a\xff3",
+        "45",
+        "1abc",
+        "abc\\",
+        "a0",
+        "a1٣",
+        "ab)2",
+        "a(b(c)2",
+        "a()3",
+        "(a0)",
+        "a123456789012",
+        "((a9999999999)9999999999)9999999999",
+        "(a99999999999)9999999",
+        "(é2)134217729",
+        strings.Repeat("(", 101) + "a" + strings.Repeat(")", 101),
+    }
+
+    for _, input := range inputs {
+        t.Run(input, func(t *testing.T) {
+            expected, expectedErr := Unpack(input)
+
+            result, err := AppendUnpack([]byte("prefix:"), input)
+            if !reflect.DeepEqual(err, expectedErr) {
+                t.Fatalf("AppendUnpack(%q) error = %v, want %v", input, err, expectedErr)
+            }
+            if err != nil {
+                expected = ""
+            }
+            if string(result) != "prefix:"+expected {
+                t.Errorf("AppendUnpack(%q) = %q, want %q", input, result, "prefix:"+expected)
+            }
+        })
+    }
+}
+
+func TestAppendUnpackAllocs(t *testing.T) {
+    buf := make([]byte, 0, 64)
+    allocs := testing.AllocsPerRun(100, func() {
+        buf, _ = AppendUnpack(buf[:0], "a4bc2d5e\\4\\5qwe\\45(xy)3")
+    })
+    if allocs != 0 {
+        t.Errorf("AppendUnpack() allocs = %v, want 0", allocs)
+    }
+}
+
+func FuzzAppendUnpack(f *testing.F) {
+    for _, seed := range []string{"", "a4bc2d5e", "qwe\\45", "(ab2)3c", "((a)2b)2", "a(b"} {
+        f.Add(seed)
+    }
+
+    f.Fuzz(func(t *testing.T, s string) {
+        if !utf8.ValidString(s) {
+            t.Skip()
+        }
+
+        expected, err := UnpackWithOptions(s, &Options{MaxOutputRunes: 1 << 16})
+        if errors.Is(err, ErrTooLarge) {
+            t.Skip()
+        }
+
+        result, appendErr := AppendUnpack(nil, s)
+        if !reflect.DeepEqual(appendErr, err) {
+            t.Fatalf("AppendUnpack(%q) error = %v, want %v", s, appendErr, err)
+        }
+        if err == nil && string(result) != expected {
+            t.Fatalf("AppendUnpack(%q) = %q, want %q", s, result, expected)
+        }
+    })
+}
+
+var benchmarkInputs = []struct {
+    name  string
+    input string
+}{
+    {"short", "a4bc2d5e\\4\\5qwe\\45"},
+    {"long runs", "a1000b1000\\41000é1000"},
+    {"groups", "((ab2)10c)10(日本)50"},
+}
+
+func BenchmarkUnpackInputs(b *testing.B) {
+    for _, bm := range benchmarkInputs {
+        b.Run(bm.name, func(b *testing.B) {
+            b.ReportAllocs()
+            for i := 0; i < b.N; i++ {
+                Unpack(bm.input)
+            }
+        })
+    }
+}
+
+func BenchmarkAppendUnpack(b *testing.B) {
+    for _, bm := range benchmarkInputs {
+        b.Run(bm.name, func(b *testing.B) {
+            b.ReportAllocs()
+            var buf []byte
+            for i := 0; i < b.N; i++ {
+                buf, _ = AppendUnpack(buf[:0], bm.input)
+            }
+        })
+    }
+}
@@ -0,0 +1,155 @@
+package unpack
+
+import (
+    "encoding/binary"
+    "errors"
+    "fmt"
+    "hash/crc32"
+)
+
+// magic starts every frame written by PackBytes. Its first byte is not
+// valid UTF-8, so a frame cannot be mistaken for the text format.
+const magic = "\x89UPK"
+
+// A frame is the magic, the unpacked length as a big-endian uint64 and the
+// CRC-32 (IEEE) of the unpacked data, followed by the runs.
+const headerSize = len(magic) + 8 + 4
+
+// minByteRun is the shortest run of a byte PackBytes encodes as a repeat.
+const minByteRun = 3
+
+// ErrCorrupt is returned when packed binary data is malformed.
+var ErrCorrupt = errors.New("corrupt packed data")
+
+// ErrChecksum is returned when packed binary data unpacks to something
+// other than what was packed. It satisfies errors.Is(err, ErrCorrupt).
+var ErrChecksum = fmt.Errorf("%w: checksum mismatch", ErrCorrupt)
+
+// PackBytes packs arbitrary binary data into a frame. Each run is a uvarint
+// header, the run length shifted left by one, followed by the literal bytes
+// if the low bit is clear or by the repeated byte if it is set.
+func PackBytes(data []byte) []byte {
+    out := make([]byte, headerSize, headerSize+len(data)/2+binary.MaxVarintLen64)
+    copy(out, magic)
+    binary.BigEndian.PutUint64(out[len(magic):], uint64(len(data)))
+    binary.BigEndian.PutUint32(out[len(magic)+8:], crc32.ChecksumIEEE(data))
+
+    literal := 0
+    for i := 0; i < len(data); {
+        n := 1
+        for i+n < len(data) && data[i+n] == data[i] {
+            n++
+        }
+        if n < minByteRun {
+            i += n
+            continue
+        }
+
+        out = appendLiteral(out, data[literal:i])
+        out = binary.AppendUvarint(out, uint64(n)<<1|1)
+        out = append(out, data[i])
+        i += n
+        literal = i
+    }
+
+    return appendLiteral(out, data[literal:])
+}
+
+func appendLiteral(out, literal []byte) []byte {
+    if len(literal) == 0 {
+        return out
+    }
+    out = binary.AppendUvarint(out, uint64(len(literal))<<1)
+    return append(out, literal...)
+}
+
+// DefaultMaxOutputBytes bounds the output of UnpackBytes when
+// Options.MaxOutputBytes is zero. A frame may claim any size, so callers
+// expecting larger data must raise the limit explicitly.
+const DefaultMaxOutputBytes = 1 << 30
+
+// UnpackBytes unpacks a frame written by PackBytes and verifies it. Frames
+// larger than DefaultMaxOutputBytes are rejected with ErrTooLarge.
+func UnpackBytes(packed []byte) ([]byte, error) {
+    return UnpackBytesWithOptions(packed, nil)
+}
+
+// UnpackBytesWithOptions unpacks a frame, rejecting one larger than
+// opts.MaxOutputBytes, or DefaultMaxOutputBytes if zero, before allocating
+// the output.
+func UnpackBytesWithOptions(packed []byte, opts *Options) ([]byte, error) {
+    if opts == nil {
+        opts = &Options{}
+    }
+
+    if len(packed) < headerSize || string(packed[:len(magic)]) != magic {
+        return nil, ErrCorrupt
+    }
+    size := binary.BigEndian.Uint64(packed[len(magic):])
+    sum := binary.BigEndian.Uint32(packed[len(magic)+8:])
+    payload := packed[headerSize:]
+
+    limit := DefaultMaxOutputBytes
+    if opts.MaxOutputBytes > 0 {
+        limit = opts.MaxOutputBytes
+    }
+    if size > uint64(limit) {
+        return nil, ErrTooLarge
+    }
+    // Check that the runs add up to the recorded length before allocating it.
+    total, err := byteRunsLen(payload)
+    if err != nil {
+        return nil, err
+    }
+    if total != size {
+        return nil, ErrCorrupt
+    }
+
+    data := make([]byte, 0, size)
+    for len(payload) > 0 {
+        header, k := binary.Uvarint(payload)
+        payload = payload[k:]
+        n := int(header >> 1)
+
+        if header&1 == 0 {
+            data = append(data, payload[:n]...)
+            payload = payload[n:]
+            continue
+        }
+        start := len(data)
+        data = appendRepeat(append(data, payload[0]), start, n)
+        payload = payload[1:]
+    }
+
+    if crc32.ChecksumIEEE(data) != sum {
+        return nil, ErrChecksum
+    }
+    return data, nil
+}
+
+// byteRunsLen checks the runs in payload and returns their unpacked length.
+func byteRunsLen(payload []byte) (uint64, error) {
+    var total uint64
+    for len(payload) > 0 {
+        header, k := binary.Uvarint(payload)
+        if k <= 0 {
+            return 0, ErrCorrupt
+        }
+        payload = payload[k:]
+
+        n := header >> 1
+        if n == 0 {
+            return 0, ErrCorrupt
+        }
+        width := uint64(1)
+        if header&1 == 0 {
+            width = n
+        }
+        if uint64(len(payload)) < width || total+n < total {
+            return 0, ErrCorrupt
+        }
+        payload = payload[width:]
+        total += n
+    }
+    return total, nil
+}
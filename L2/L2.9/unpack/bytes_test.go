@@ -0,0 +1,174 @@
+package unpack
+
+import (
+    "bytes"
+    "encoding/binary"
+    "errors"
+    "testing"
+)
+
+func TestPackBytes(t *testing.T) {
+    tests := []struct {
+        name     string
+        input    []byte
+        expected []byte
+    }{
+        {
+            name:     "empty",
+            input:    nil,
+            expected: nil,
+        },
+        {
+            name:     "literal",
+            input:    []byte{1, 2, 2},
+            expected: []byte{6, 1, 2, 2},
+        },
+        {
+            name:     "runs",
+            input:    []byte{0, 0, 0, 0, 7, 0xff, 0xff, 0xff},
+            expected: []byte{9, 0, 2, 7, 7, 0xff},
+        },
+        {
+            name:     "long run",
+            input:    make([]byte, 1000),
+            expected: []byte{0xd1, 0x0f, 0},
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            packed := PackBytes(tt.input)
+            if !bytes.HasPrefix(packed, []byte(magic)) {
+                t.Fatalf("PackBytes() = %x, missing magic", packed)
+            }
+            if payload := packed[headerSize:]; !bytes.Equal(payload, tt.expected) {
+                t.Errorf("PackBytes() payload = %x, want %x", payload, tt.expected)
+            }
+
+            result, err := UnpackBytes(packed)
+            if err != nil {
+                t.Fatalf("UnpackBytes() unexpected error: %v", err)
+            }
+            if !bytes.Equal(result, tt.input) {
+                t.Errorf("UnpackBytes() = %x, want %x", result, tt.input)
+            }
+        })
+    }
+}
+
+func TestUnpackBytesErrors(t *testing.T) {
+    valid := PackBytes([]byte("sparse\x00\x00\x00\x00\x00\x00dump"))
+    modify := func(f func(b []byte) []byte) []byte {
+        return f(bytes.Clone(valid))
+    }
+
+    tests := []struct {
+        name  string
+        input []byte
+        opts  *Options
+        err   error
+    }{
+        {
+            name:  "text format",
+            input: []byte("a4bc2d5e"),
+            err:   ErrCorrupt,
+        },
+        {
+            name:  "truncated header",
+            input: valid[:headerSize-1],
+            err:   ErrCorrupt,
+        },
+        {
+            name:  "truncated runs",
+            input: valid[:len(valid)-1],
+            err:   ErrCorrupt,
+        },
+        {
+            name: "checksum",
+            input: modify(func(b []byte) []byte {
+                b[len(b)-1] ^= 1
+                return b
+            }),
+            err: ErrChecksum,
+        },
+        {
+            name: "length mismatch",
+            input: modify(func(b []byte) []byte {
+                binary.BigEndian.PutUint64(b[len(magic):], 17)
+                return b
+            }),
+            err: ErrCorrupt,
+        },
+        {
+            name: "zero length run",
+            input: modify(func(b []byte) []byte {
+                return append(b, 1, 'x')
+            }),
+            err: ErrCorrupt,
+        },
+        {
+            name:  "too large",
+            input: valid,
+            opts:  &Options{MaxOutputBytes: 15},
+            err:   ErrTooLarge,
+        },
+        {
+            name: "bomb",
+            input: modify(func(b []byte) []byte {
+                binary.BigEndian.PutUint64(b[len(magic):], 1<<40)
+                return binary.AppendUvarint(b[:headerSize], 1<<41|1)
+            }),
+            opts: &Options{MaxOutputBytes: 1 << 20},
+            err:  ErrTooLarge,
+        },
+        {
+            name: "bomb without options",
+            input: modify(func(b []byte) []byte {
+                binary.BigEndian.PutUint64(b[len(magic):], 1<<40)
+                return binary.AppendUvarint(b[:headerSize], 1<<41|1)
+            }),
+            opts: nil,
+            err:  ErrTooLarge,
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if _, err := UnpackBytesWithOptions(tt.input, tt.opts); !errors.Is(err, tt.err) {
+                t.Errorf("UnpackBytesWithOptions() error = %v, want %v", err, tt.err)
+            }
+        })
+    }
+}
+
+func FuzzPackBytes(f *testing.F) {
+    for _, seed := range [][]byte{nil, {0, 0, 0}, []byte("aab\x00\x00\x00\x00c")} {
+        f.Add(seed)
+    }
+
+    f.Fuzz(func(t *testing.T, data []byte) {
+        result, err := UnpackBytes(PackBytes(data))
+        if err != nil {
+            t.Fatalf("UnpackBytes(PackBytes(%x)) unexpected error: %v", data, err)
+        }
+        if !bytes.Equal(result, data) {
+            t.Fatalf("UnpackBytes(PackBytes(%x)) = %x", data, result)
+        }
+    })
+}
+
+func FuzzUnpackBytes(f *testing.F) {
+    f.Add(PackBytes([]byte("aab\x00\x00\x00\x00c")))
+
+    f.Fuzz(func(t *testing.T, packed []byte) {
+        // Malformed frames must fail cleanly; valid ones must survive a
+        // round trip through PackBytes.
+        data, err := UnpackBytesWithOptions(packed, &Options{MaxOutputBytes: 1 << 20})
+        if err != nil {
+            return
+        }
+        if result, err := UnpackBytes(PackBytes(data)); err != nil || !bytes.Equal(result, data) {
+            t.Fatalf("UnpackBytes(PackBytes(%x)) = %x, %v", data, result, err)
+        }
+    })
+}
@@ -0,0 +1,122 @@
+package unpack
+
+import (
+    "errors"
+    "fmt"
+    "math"
+    "strconv"
+    "strings"
+    "unicode"
+    "unicode/utf8"
+)
+
+// maxCountDigits is the longest repeat count accepted by default.
+const maxCountDigits = 11
+
+// Dialect describes a packed notation. The zero value is the notation
+// Unpack and Pack use by default: a backslash escape and counts of up to
+// 11 digits following what they repeat, e.g. "a3".
+type Dialect struct {
+    // Escape is the escape rune, a backslash if zero. It must not be a
+    // digit or a parenthesis, or the dialect is rejected with
+    // ErrInvalidDialect.
+    Escape rune
+
+    // AllowZero accepts a count of 0, which deletes the rune or group.
+    AllowZero bool
+
+    // MaxDigits is the longest count accepted, 11 if zero.
+    MaxDigits int
+
+    // PrefixCount puts counts before what they repeat, e.g. "3a".
+    PrefixCount bool
+}
+
+// ErrInvalidDialect is returned for a Dialect that cannot be read back.
+var ErrInvalidDialect = errors.New("invalid dialect")
+
+// check rejects an escape rune that would be read as a count or a group.
+func (d Dialect) check() error {
+    if r := d.escape(); !utf8.ValidRune(r) || r == '(' || r == ')' || unicode.IsDigit(r) {
+        return fmt.Errorf("%w: escape %q", ErrInvalidDialect, r)
+    }
+    return nil
+}
+
+func (d Dialect) escape() rune {
+    if d.Escape == 0 {
+        return '\\'
+    }
+    return d.Escape
+}
+
+func (d Dialect) maxDigits() int {
+    if d.MaxDigits <= 0 {
+        return maxCountDigits
+    }
+    return d.MaxDigits
+}
+
+// maxCount is the largest count d can write.
+func (d Dialect) maxCount() int {
+    n := 1
+    for i := 0; i < d.maxDigits() && n <= math.MaxInt/10; i++ {
+        n *= 10
+    }
+    return n - 1
+}
+
+func (d Dialect) needsEscape(r rune) bool {
+    return r == d.escape() || r == '(' || r == ')' || unicode.IsDigit(r)
+}
+
+// Convert rewrites s from one dialect to another without unpacking it, so
+// it is safe on untrusted input. Runs and groups are kept as they are,
+// except that zero counts are dropped and counts too long for to are
+// split.
+func Convert(s string, from, to Dialect) (string, error) {
+    if err := errors.Join(from.check(), to.check()); err != nil {
+        return "", err
+    }
+
+    runs, err := parseRuns(s, &Options{Dialect: from})
+    if err != nil {
+        return "", err
+    }
+
+    var b strings.Builder
+    writeRuns(&b, runs, to)
+    return b.String(), nil
+}
+
+// writeRuns writes runs in packed form.
+func writeRuns(w runeWriter, runs []run, d Dialect) {
+    for _, r := range runs {
+        if r.group == nil {
+            writeRun(w, r.text, r.count, d)
+            continue
+        }
+        writeCounted(w, r.count, d, func() {
+            w.WriteRune('(')
+            writeRuns(w, r.group, d)
+            w.WriteRune(')')
+        })
+    }
+}
+
+// writeCounted writes count repetitions of what elem writes, splitting
+// counts too long for d.
+func writeCounted(w runeWriter, count int, d Dialect, elem func()) {
+    for count > 0 {
+        n := min(count, d.maxCount())
+        count -= n
+
+        if n > 1 && d.PrefixCount {
+            w.WriteString(strconv.Itoa(n))
+        }
+        elem()
+        if n > 1 && !d.PrefixCount {
+            w.WriteString(strconv.Itoa(n))
+        }
+    }
+}
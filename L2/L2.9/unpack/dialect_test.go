@@ -0,0 +1,226 @@
+package unpack
+
+import (
+    "errors"
+    "strings"
+    "testing"
+    "unicode/utf8"
+)
+
+var (
+    percentDialect = Dialect{Escape: '%', AllowZero: true, MaxDigits: 2}
+    prefixDialect  = Dialect{PrefixCount: true}
+)
+
+func TestUnpackDialect(t *testing.T) {
+    tests := []struct {
+        name     string
+        input    string
+        dialect  Dialect
+        expected string
+        err      error
+    }{
+        {
+            name:     "default",
+            input:    "a3\\4(bc)2",
+            dialect:  Dialect{},
+            expected: "aaa4bcbc",
+        },
+        {
+            name:     "escape rune",
+            input:    "%42\\3",
+            dialect:  percentDialect,
+            expected: "44\\\\\\",
+        },
+        {
+            name:     "zero count deletes",
+            input:    "ab0(cd)0e",
+            dialect:  percentDialect,
+            expected: "ae",
+        },
+        {
+            name:    "zero count rejected",
+            input:   "ab0",
+            dialect: Dialect{},
+            err:     ErrInvalidString,
+        },
+        {
+            name:    "too many digits",
+            input:   "a100",
+            dialect: percentDialect,
+            err:     ErrTooLarge,
+        },
+        {
+            name:     "prefix counts",
+            input:    "3ab2(c2\\4)",
+            dialect:  prefixDialect,
+            expected: "aaabc44c44",
+        },
+        {
+            name:    "prefix count at end",
+            input:   "a3",
+            dialect: prefixDialect,
+            err:     ErrInvalidString,
+        },
+        {
+            name:    "prefix count before parenthesis",
+            input:   "(a3)",
+            dialect: prefixDialect,
+            err:     ErrInvalidString,
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            result, err := UnpackWithOptions(tt.input, &Options{Dialect: tt.dialect})
+            if !errors.Is(err, tt.err) {
+                t.Fatalf("UnpackWithOptions(%q) error = %v, want %v", tt.input, err, tt.err)
+            }
+            if result != tt.expected {
+                t.Errorf("UnpackWithOptions(%q) = %q, want %q", tt.input, result, tt.expected)
+            }
+        })
+    }
+}
+
+func TestPackDialect(t *testing.T) {
+    tests := []struct {
+        name     string
+        input    string
+        dialect  Dialect
+        expected string
+    }{
+        {
+            name:     "escape rune",
+            input:    "a%%4\\",
+            dialect:  percentDialect,
+            expected: "a%%2%4\\",
+        },
+        {
+            name:     "split long counts",
+            input:    strings.Repeat("a", 106),
+            dialect:  percentDialect,
+            expected: "a99a7",
+        },
+        {
+            name:     "prefix counts",
+            input:    "aaab44",
+            dialect:  prefixDialect,
+            expected: "3ab2\\4",
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if result := PackWithOptions(tt.input, &Options{Dialect: tt.dialect}); result != tt.expected {
+                t.Errorf("PackWithOptions(%q) = %q, want %q", tt.input, result, tt.expected)
+            }
+        })
+    }
+}
+
+func TestConvert(t *testing.T) {
+    tests := []struct {
+        name     string
+        input    string
+        from, to Dialect
+        expected string
+    }{
+        {
+            name:     "to prefix",
+            input:    "a3\\4(bc2)2",
+            from:     Dialect{},
+            to:       prefixDialect,
+            expected: "3a\\42(b2c)",
+        },
+        {
+            name:     "from prefix",
+            input:    "3a\\42(b2c)",
+            from:     prefixDialect,
+            to:       Dialect{},
+            expected: "a3\\4(bc2)2",
+        },
+        {
+            name:     "escape rune",
+            input:    "%4\\2%%",
+            from:     percentDialect,
+            to:       Dialect{},
+            expected: "\\4\\\\2%",
+        },
+        {
+            name:     "zero counts dropped",
+            input:    "ab0(c0)3d",
+            from:     percentDialect,
+            to:       Dialect{},
+            expected: "ad",
+        },
+        {
+            name:     "long counts split",
+            input:    "(ab)250",
+            from:     Dialect{},
+            to:       percentDialect,
+            expected: "(ab)99(ab)99(ab)52",
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            result, err := Convert(tt.input, tt.from, tt.to)
+            if err != nil {
+                t.Fatalf("Convert(%q) unexpected error: %v", tt.input, err)
+            }
+            if result != tt.expected {
+                t.Errorf("Convert(%q) = %q, want %q", tt.input, result, tt.expected)
+            }
+        })
+    }
+}
+
+func TestInvalidDialect(t *testing.T) {
+    for _, d := range []Dialect{{Escape: '3'}, {Escape: '٣'}, {Escape: '('}, {Escape: ')'}, {Escape: -1}} {
+        if _, err := UnpackWithOptions("a3", &Options{Dialect: d}); !errors.Is(err, ErrInvalidDialect) {
+            t.Errorf("UnpackWithOptions() with escape %q error = %v, want %v", d.Escape, err, ErrInvalidDialect)
+        }
+        if _, err := Convert("a\\3", Dialect{}, d); !errors.Is(err, ErrInvalidDialect) {
+            t.Errorf("Convert() to escape %q error = %v, want %v", d.Escape, err, ErrInvalidDialect)
+        }
+        if _, err := Convert("a3", d, Dialect{}); !errors.Is(err, ErrInvalidDialect) {
+            t.Errorf("Convert() from escape %q error = %v, want %v", d.Escape, err, ErrInvalidDialect)
+        }
+        func() {
+            defer func() {
+                if recover() == nil {
+                    t.Errorf("PackWithOptions() with escape %q did not panic", d.Escape)
+                }
+            }()
+            PackWithOptions("a3", &Options{Dialect: d})
+        }()
+    }
+}
+
+func FuzzConvert(f *testing.F) {
+    for _, seed := range []string{"", "aaab44", "%%%", "\\\\(()", "ééé日"} {
+        f.Add(seed)
+    }
+
+    dialects := []Dialect{{}, percentDialect, prefixDialect}
+    f.Fuzz(func(t *testing.T, s string) {
+        if !utf8.ValidString(s) {
+            t.Skip()
+        }
+
+        for _, from := range dialects {
+            for _, to := range dialects {
+                packed := PackWithOptions(s, &Options{Dialect: from})
+                converted, err := Convert(packed, from, to)
+                if err != nil {
+                    t.Fatalf("Convert(%q, %+v, %+v) unexpected error: %v", packed, from, to, err)
+                }
+                result, err := UnpackWithOptions(converted, &Options{Dialect: to})
+                if err != nil || result != s {
+                    t.Fatalf("UnpackWithOptions(%q, %+v) = %q, %v, want %q", converted, to, result, err, s)
+                }
+            }
+        }
+    })
+}
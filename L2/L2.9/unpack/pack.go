@@ -0,0 +1,116 @@
+package unpack
+
+import (
+    "slices"
+    "strconv"
+    "strings"
+    "unicode/utf8"
+)
+
+// Pack function to pack strings, the inverse of Unpack.
+// Runs of a rune are written as the rune and its count; digits,
+// backslashes and parentheses are escaped, so Unpack(Pack(s)) == s for any
+// valid UTF-8 s.
+func Pack(s string) string {
+    return PackWithOptions(s, nil)
+}
+
+// PackWithOptions packs s in opts.Dialect, repeating grapheme clusters
+// instead of runes if opts.Graphemes is set. The size limits do not apply
+// to packing. It panics if opts.Dialect is invalid, as the output could not
+// be unpacked.
+func PackWithOptions(s string, opts *Options) string {
+    if opts == nil {
+        opts = &Options{}
+    }
+    if err := opts.Dialect.check(); err != nil {
+        panic(err)
+    }
+
+    var b strings.Builder
+    for s != "" {
+        unit, size := nextUnit(s, opts.Graphemes)
+        s = s[size:]
+
+        count := 1
+        for s != "" {
+            next, size := nextUnit(s, opts.Graphemes)
+            if next != unit {
+                break
+            }
+            s = s[size:]
+            count++
+        }
+
+        writeRun(&b, unit, count, opts.Dialect)
+    }
+
+    return b.String()
+}
+
+// maxGroupPeriod is the longest repeated substring PackGroups looks for.
+const maxGroupPeriod = 64
+
+// PackGroups is like Pack, but also encodes a substring repeated back to
+// back as a group, e.g. "abbabbabbc" as "(ab2)3c", when that is shorter.
+func PackGroups(s string) string {
+    var b strings.Builder
+    runes := []rune(s)
+
+    for i := 0; i < len(runes); {
+        // Start from the single-rune run Pack would write and look for a
+        // repeated substring that saves more.
+        period, count := 1, repeats(runes[i:], 1)
+        saved := max(count-1-len(strconv.Itoa(count)), 0)
+        var unit string
+        for n := 2; n <= maxGroupPeriod && 2*n <= len(runes)-i; n++ {
+            k := repeats(runes[i:], n)
+            if k < 2 {
+                continue
+            }
+            packed := PackGroups(string(runes[i : i+n]))
+            size := utf8.RuneCountInString(packed)
+            if gain := (k-1)*size - 2 - len(strconv.Itoa(k)); gain > saved {
+                period, count, saved, unit = n, k, gain, packed
+            }
+        }
+
+        if period == 1 {
+            writeRun(&b, string(runes[i]), count, Dialect{})
+        } else {
+            b.WriteByte('(')
+            b.WriteString(unit)
+            b.WriteByte(')')
+            b.WriteString(strconv.Itoa(count))
+        }
+        i += period * count
+    }
+
+    return b.String()
+}
+
+// repeats counts how many times the first n runes of runes occur back to
+// back at its start.
+func repeats(runes []rune, n int) int {
+    count := 1
+    for (count+1)*n <= len(runes) && slices.Equal(runes[:n], runes[count*n:(count+1)*n]) {
+        count++
+    }
+    return count
+}
+
+type runeWriter interface {
+    WriteRune(r rune) (int, error)
+    WriteString(s string) (int, error)
+}
+
+// writeRun writes a run of count units in packed form. A unit is escaped
+// if its first rune needs it.
+func writeRun(w runeWriter, unit string, count int, d Dialect) {
+    writeCounted(w, count, d, func() {
+        if r, _ := utf8.DecodeRuneInString(unit); d.needsEscape(r) {
+            w.WriteRune(d.escape())
+        }
+        w.WriteString(unit)
+    })
+}
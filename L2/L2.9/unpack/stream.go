@@ -0,0 +1,199 @@
+package unpack
+
+import (
+    "bufio"
+    "io"
+    "strings"
+    "unicode"
+)
+
+// UnpackStream unpacks r into w rune by rune. Memory is bounded by the
+// longest group in the input, regardless of the output size, and groups
+// longer than DefaultMaxOutputRunes bytes are rejected with ErrTooLarge. On error the
+// output written so far is left in w.
+func UnpackStream(r io.Reader, w io.Writer) error {
+    out := bufio.NewWriter(w)
+    err := unpackStream(bufio.NewReader(r), out)
+    if flushErr := out.Flush(); err == nil {
+        err = flushErr
+    }
+    return err
+}
+
+// unpackStream does the work of UnpackStream, stopping as soon as out
+// fails.
+func unpackStream(in *bufio.Reader, out *bufio.Writer) error {
+    offset := 0
+    for {
+        start := offset
+        current, size, err := in.ReadRune()
+        if err == io.EOF {
+            break
+        }
+        if err != nil {
+            return err
+        }
+        offset += size
+
+        var group []run
+        switch {
+        case current == '\\':
+            current, size, err = in.ReadRune()
+            if err == io.EOF {
+                return &SyntaxError{Offset: start, Rune: '\\', Reason: "unterminated escape"}
+            }
+            if err != nil {
+                return err
+            }
+            offset += size
+        case current == '(':
+            var n int
+            group, n, err = readGroup(in, start, DefaultMaxOutputRunes)
+            if err != nil {
+                return err
+            }
+            offset += n
+        case current == ')':
+            return &SyntaxError{Offset: start, Rune: current, Reason: "unmatched ')'"}
+        case unicode.IsDigit(current):
+            return &SyntaxError{Offset: start, Rune: current, Reason: "count without a preceding rune"}
+        }
+
+        count, n, err := readCount(in, offset)
+        if err != nil {
+            return err
+        }
+        offset += n
+        for j := 0; j < count; j++ {
+            if group != nil {
+                expand(out, group)
+                err = writeErr(out)
+            } else {
+                _, err = out.WriteRune(current)
+            }
+            if err != nil {
+                return err
+            }
+        }
+    }
+
+    return nil
+}
+
+// writeErr returns the first error out met. A bufio.Writer keeps it and
+// returns it from every later write, so an empty one reports it.
+func writeErr(out *bufio.Writer) error {
+    _, err := out.WriteString("")
+    return err
+}
+
+// readGroup reads the rest of the group opened at offset and parses it,
+// returning its runs and the number of bytes read. Groups longer than limit
+// bytes are rejected with ErrTooLarge.
+func readGroup(in *bufio.Reader, offset, limit int) ([]run, int, error) {
+    var b strings.Builder
+    b.WriteRune('(')
+
+    // Reading stops at a group nested too deeply, which the parser reports.
+    for depth := 1; depth > 0 && depth <= maxDepth; {
+        if b.Len() > limit {
+            return nil, 0, ErrTooLarge
+        }
+        r, _, err := in.ReadRune()
+        if err == io.EOF {
+            break
+        }
+        if err != nil {
+            return nil, 0, err
+        }
+        b.WriteRune(r)
+
+        switch r {
+        case '\\':
+            if r, _, err = in.ReadRune(); err == nil {
+                b.WriteRune(r)
+            } else if err != io.EOF {
+                return nil, 0, err
+            }
+        case '(':
+            depth++
+        case ')':
+            depth--
+        }
+    }
+
+    // The parser reports unclosed groups and any error inside the group.
+    p := &parser{s: b.String(), base: offset, opts: &Options{}}
+    runs, err := p.parse(-1)
+    if err != nil {
+        return nil, 0, err
+    }
+    return runs[0].group, b.Len() - 1, nil
+}
+
+// readCount reads the repeat count at offset, 1 if there is none, and
+// returns it with its length in bytes.
+func readCount(in *bufio.Reader, offset int) (int, int, error) {
+    var digits []rune
+    n := 0
+    for {
+        r, size, err := in.ReadRune()
+        if err == io.EOF {
+            break
+        }
+        if err != nil {
+            return 0, 0, err
+        }
+        if !unicode.IsDigit(r) {
+            in.UnreadRune()
+            break
+        }
+        if len(digits) == maxCountDigits {
+            return 0, 0, ErrTooLarge
+        }
+        digits = append(digits, r)
+        n += size
+    }
+
+    if len(digits) == 0 {
+        return 1, 0, nil
+    }
+
+    count, err := parseCount(string(digits), offset, Dialect{})
+    return count, n, err
+}
+
+// PackStream packs r into w rune by rune, the streaming counterpart of Pack.
+func PackStream(r io.Reader, w io.Writer) error {
+    in := bufio.NewReader(r)
+    out := bufio.NewWriter(w)
+
+    var current rune
+    count := 0
+    for {
+        next, _, err := in.ReadRune()
+        if err == io.EOF {
+            break
+        }
+        if err != nil {
+            return err
+        }
+
+        if count > 0 && next == current {
+            count++
+            continue
+        }
+        if count > 0 {
+            writeRun(out, string(current), count, Dialect{})
+            if err := writeErr(out); err != nil {
+                return err
+            }
+        }
+        current, count = next, 1
+    }
+    if count > 0 {
+        writeRun(out, string(current), count, Dialect{})
+    }
+
+    return out.Flush()
+}
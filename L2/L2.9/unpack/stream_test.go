@@ -0,0 +1,155 @@
+package unpack
+
+import (
+    "bufio"
+    "bytes"
+    "io"
+    "reflect"
+    "strings"
+    "testing"
+    "testing/iotest"
+)
+
+func TestUnpackStream(t *testing.T) {
+    inputs := []string{
+        "a4bc2d5e",
+        "abcd",
+        "45",
+        "",
+        "qwe\\4\\5",
+        "qwe\\45",
+        "qwe\\\\5",
+        "1abc",
+        "abc\\",
+        "a0",
+        "a10",
+        "é3日",
+        "a1b2c3",
+        "a123456789012",
+        "ab00",
+        "a1٣",
+        "(ab2)3c",
+        "x((ab)2\\)c)2y",
+        "ab)2",
+        "a(b(c)2",
+        "(a\\",
+        "a()3",
+        "(a0)",
+        strings.Repeat("(", 101) + "a" + strings.Repeat(")", 101),
+        "(1" + strings.Repeat("(", 101),
+    }
+
+    for _, input := range inputs {
+        t.Run(input, func(t *testing.T) {
+            expected, expectedErr := Unpack(input)
+
+            var output bytes.Buffer
+            err := UnpackStream(strings.NewReader(input), &output)
+
+            if !reflect.DeepEqual(err, expectedErr) {
+                t.Fatalf("UnpackStream(%q) error = %v, want %v", input, err, expectedErr)
+            }
+            if err == nil && output.String() != expected {
+                t.Errorf("UnpackStream(%q) = %q, want %q", input, output.String(), expected)
+            }
+        })
+    }
+}
+
+func TestReadGroupLimit(t *testing.T) {
+    in := bufio.NewReader(strings.NewReader("abcdefgh" + strings.Repeat("x", 1000)))
+    if _, _, err := readGroup(in, 0, 4); err != ErrTooLarge {
+        t.Errorf("readGroup() error = %v, want %v", err, ErrTooLarge)
+    }
+    if in.Buffered() == 0 {
+        t.Error("readGroup() read past the limit")
+    }
+
+    in = bufio.NewReader(strings.NewReader("ab)2"))
+    if runs, n, err := readGroup(in, 0, 4); err != nil || len(runs) != 2 || n != 3 {
+        t.Errorf("readGroup() = %v, %d, %v, want two runs in 3 bytes", runs, n, err)
+    }
+}
+
+func TestPackStream(t *testing.T) {
+    for _, input := range []string{"", "a", "aaaabccddddde", "qwe44444", "\\\\\\", "ééé日"} {
+        var output bytes.Buffer
+        if err := PackStream(strings.NewReader(input), &output); err != nil {
+            t.Fatalf("PackStream(%q) unexpected error: %v", input, err)
+        }
+        if output.String() != Pack(input) {
+            t.Errorf("PackStream(%q) = %q, want %q", input, output.String(), Pack(input))
+        }
+    }
+}
+
+// countingWriter discards its input and counts the bytes.
+type countingWriter struct {
+    n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+    w.n += int64(len(p))
+    return len(p), nil
+}
+
+func TestUnpackStreamLargeOutput(t *testing.T) {
+    var output countingWriter
+    if err := UnpackStream(strings.NewReader("a50000000b"), &output); err != nil {
+        t.Fatalf("UnpackStream() unexpected error: %v", err)
+    }
+    if output.n != 50000001 {
+        t.Errorf("UnpackStream() wrote %d bytes, want 50000001", output.n)
+    }
+}
+
+func TestUnpackStreamPartialOutput(t *testing.T) {
+    var output bytes.Buffer
+    if err := UnpackStream(strings.NewReader("a3bc\\"), &output); err == nil {
+        t.Fatal("UnpackStream() expected error, got none")
+    }
+    if output.String() != "aaabc" {
+        t.Errorf("UnpackStream() left %q, want %q", output.String(), "aaabc")
+    }
+}
+
+// failingWriter accepts n bytes and then fails.
+type failingWriter struct {
+    n int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+    if len(p) > w.n {
+        written := w.n
+        w.n = 0
+        return written, io.ErrShortWrite
+    }
+    w.n -= len(p)
+    return len(p), nil
+}
+
+func TestUnpackStreamWriterError(t *testing.T) {
+    for _, input := range []string{"a99999999999", "(ab)99999999999"} {
+        err := UnpackStream(strings.NewReader(input), &failingWriter{n: 10})
+        if err != io.ErrShortWrite {
+            t.Errorf("UnpackStream(%q) error = %v, want %v", input, err, io.ErrShortWrite)
+        }
+    }
+}
+
+func TestPackStreamWriterError(t *testing.T) {
+    in := strings.NewReader(strings.Repeat("ab", 1<<20))
+    if err := PackStream(in, &failingWriter{n: 10}); err != io.ErrShortWrite {
+        t.Errorf("PackStream() error = %v, want %v", err, io.ErrShortWrite)
+    }
+    if in.Len() == 0 {
+        t.Error("PackStream() read all of its input after the writer failed")
+    }
+}
+
+func TestPackStreamReaderError(t *testing.T) {
+    r := io.MultiReader(strings.NewReader("aaa"), iotest.ErrReader(io.ErrClosedPipe))
+    if err := PackStream(r, io.Discard); err != io.ErrClosedPipe {
+        t.Errorf("PackStream() error = %v, want %v", err, io.ErrClosedPipe)
+    }
+}
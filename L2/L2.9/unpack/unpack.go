@@ -2,103 +2,356 @@ package unpack
 
 import (
     "errors"
+    "fmt"
+    "io"
+    "math"
     "strconv"
+    "strings"
     "unicode"
+    "unicode/utf8"
+
+    "github.com/rivo/uniseg"
 )
 
 // ErrInvalidString for returning errors.
 var ErrInvalidString = errors.New("invalid string")
 
+// SyntaxError describes where and why a packed string is invalid.
+type SyntaxError struct {
+    Offset int // byte offset of Rune in the input
+    Rune   rune
+    Reason string
+}
+
+func (e *SyntaxError) Error() string {
+    return fmt.Sprintf("invalid string: %s: %q at offset %d", e.Reason, e.Rune, e.Offset)
+}
+
+// Unwrap lets errors.Is(err, ErrInvalidString) match syntax errors.
+func (e *SyntaxError) Unwrap() error {
+    return ErrInvalidString
+}
+
+// ErrTooLarge is returned when the unpacked output would exceed the limits.
+var ErrTooLarge = errors.New("unpacked string too large")
+
+// DefaultMaxOutputRunes bounds the unpacked output when
+// Options.MaxOutputRunes is zero, so that a short input cannot ask for
+// more memory than can be allocated.
+const DefaultMaxOutputRunes = 1 << 28
+
+// Options limits the unpacked output.
+type Options struct {
+    // MaxOutputRunes limits the unpacked output, DefaultMaxOutputRunes
+    // if zero.
+    MaxOutputRunes int
+
+    // MaxRepeat limits each count. Zero means no limit.
+    MaxRepeat int
+
+    // MaxOutputBytes limits the output of UnpackBytesWithOptions,
+    // DefaultMaxOutputBytes if zero.
+    MaxOutputBytes int
+
+    // Graphemes repeats user-perceived characters, grapheme clusters as
+    // defined by UAX #29, instead of single runes. Pack honours it too.
+    Graphemes bool
+
+    // Dialect is the packed notation, the default one if zero.
+    Dialect Dialect
+}
+
+// maxDepth is the deepest nesting of groups accepted.
+const maxDepth = 100
+
+// run is a unit of text, or a group of runs when group is non-nil,
+// repeated count times.
+type run struct {
+    text  string
+    group []run
+    count int
+}
+
 // Unpack function to unpack strings.
 func Unpack(s string) (string, error) {
-    if s == "" {
-        return "", nil
+    return UnpackWithOptions(s, nil)
+}
+
+// UnpackWithOptions unpacks s, rejecting oversized expansions before
+// allocating the output.
+func UnpackWithOptions(s string, opts *Options) (string, error) {
+    if opts == nil {
+        opts = &Options{}
+    }
+    if err := opts.Dialect.check(); err != nil {
+        return "", err
     }
 
-    if isAllDigits(s) {
-        return "", ErrInvalidString
+    runs, err := parseRuns(s, opts)
+    if err != nil {
+        return "", err
     }
 
-    var result []rune
-    runes := []rune(s)
-    length := len(runes)
+    limit := DefaultMaxOutputRunes
+    if opts.MaxOutputRunes > 0 {
+        limit = opts.MaxOutputRunes
+    }
+    _, size, err := expandedLen(runs, limit)
+    if err != nil {
+        return "", err
+    }
 
-    for i := 0; i < length; i++ {
-        current := runes[i]
+    var b strings.Builder
+    b.Grow(size)
+    expand(&b, runs)
+    return b.String(), nil
+}
 
-    if current == '\\' {
-        if i+1 >= length {
-            return "", ErrInvalidString
-        } 
-        escapedChar := runes[i+1]
-        i++
-        if i+1 < length && unicode.IsDigit(runes[i+1]) {
-            count, digits := extractNumber(runes[i+1:])
-            if count == 0 {
-                return "", ErrInvalidString
+// expandedLen returns the unpacked length of runs in runes and in bytes,
+// or ErrTooLarge if it exceeds limit runes.
+func expandedLen(runs []run, limit int) (int, int, error) {
+    total, size := 0, 0
+    for _, r := range runs {
+        n, m := utf8.RuneCountInString(r.text), len(r.text)
+        if r.group != nil {
+            var err error
+            if n, m, err = expandedLen(r.group, limit); err != nil {
+                return 0, 0, err
             }
-            for j := 0; j < count; j++ {
-                result = append(result, escapedChar)
+        }
+        if n > 0 && r.count > (limit-total)/n || m > 0 && r.count > (math.MaxInt-size)/m {
+            return 0, 0, ErrTooLarge
+        }
+        total += n * r.count
+        size += m * r.count
+    }
+    return total, size, nil
+}
+
+// expand writes the unpacked runs to w.
+func expand(w io.StringWriter, runs []run) {
+    for _, r := range runs {
+        for j := 0; j < r.count; j++ {
+            if r.group != nil {
+                expand(w, r.group)
+            } else {
+                w.WriteString(r.text)
             }
-            i += digits
-        } else {
-            result = append(result, escapedChar)
         }
-    continue
+    }
+}
+
+func parseRuns(s string, opts *Options) ([]run, error) {
+    p := &parser{s: s, opts: opts}
+    return p.parse(-1)
+}
+
+// Validate reports every syntax error in s, or nil if s is well formed.
+// Size limits are not checked.
+func Validate(s string) []*SyntaxError {
+    var errs []*SyntaxError
+    p := &parser{s: s, opts: &Options{}, errs: &errs}
+    p.parse(-1)
+    return errs
+}
+
+// parser splits a packed string into runs. It stops at the first error,
+// unless errs is non-nil, in which case syntax errors are collected and
+// parsing resumes after the offending rune or count.
+type parser struct {
+    s     string
+    pos   int
+    base  int // offset of s in the input
+    depth int
+    opts  *Options
+    errs  *[]*SyntaxError
+}
+
+func (p *parser) fail(err error) error {
+    if p.errs == nil {
+        return err
+    }
+    // Sizes are not checked when collecting, so a count too long is
+    // skipped like any other.
+    if errors.Is(err, ErrTooLarge) {
+        return nil
+    }
+    syntaxErr, ok := err.(*SyntaxError)
+    if !ok {
+        return err
+    }
+    *p.errs = append(*p.errs, syntaxErr)
+    return nil
 }
 
-        if !unicode.IsDigit(current) {
-            if i+1 < length && unicode.IsDigit(runes[i+1]) {
-                count, digits := extractNumber(runes[i+1:])
-                if count == 0 {
-                    return "", ErrInvalidString
+func (p *parser) syntaxError(pos int, r rune, reason string) error {
+    return p.fail(&SyntaxError{Offset: p.base + pos, Rune: r, Reason: reason})
+}
+
+// parse reads runs up to the end of s or, inside the group opened at
+// byte open, up to its closing parenthesis. open is -1 at the top level.
+func (p *parser) parse(open int) ([]run, error) {
+    d := p.opts.Dialect
+
+    var runs []run
+    for p.pos < len(p.s) {
+        count := 1
+        if d.PrefixCount {
+            start := p.pos
+            var err error
+            if count, err = p.count(); err != nil {
+                if err := p.fail(err); err != nil {
+                    return nil, err
                 }
-                for j := 0; j < count; j++ {
-                    result = append(result, current)
+                continue
+            }
+            if p.pos > start && (p.pos == len(p.s) || p.s[p.pos] == ')') {
+                if err := p.syntaxError(start, rune(p.s[start]), "count without a following rune"); err != nil {
+                    return nil, err
                 }
-                i += digits
-            } else {
-                result = append(result, current)
+                continue
+            }
+        }
+
+        start := p.pos
+        current, size := utf8.DecodeRuneInString(p.s[p.pos:])
+        p.pos += size
+
+        var r run
+        isGroup := false
+        switch {
+        case current == d.escape():
+            if p.pos >= len(p.s) {
+                return nil, p.syntaxError(start, current, "unterminated escape")
+            }
+            r.text, size = nextUnit(p.s[p.pos:], p.opts.Graphemes)
+            p.pos += size
+        case current == '(':
+            if p.depth == maxDepth {
+                return nil, p.syntaxError(start, current, "groups nested too deeply")
+            }
+            p.depth++
+            group, err := p.parse(start)
+            p.depth--
+            if err != nil {
+                return nil, err
             }
-        } else {
-            return "", ErrInvalidString
+            if p.s[start+size:p.pos] == ")" {
+                if err := p.syntaxError(start, current, "empty group"); err != nil {
+                    return nil, err
+                }
+            }
+            r.group, isGroup = group, true
+        case current == ')':
+            if open >= 0 {
+                return runs, nil
+            }
+            if !d.PrefixCount {
+                p.count()
+            }
+            if err := p.syntaxError(start, current, "unmatched ')'"); err != nil {
+                return nil, err
+            }
+            continue
+        case unicode.IsDigit(current):
+            p.pos = start
+            p.count()
+            if err := p.syntaxError(start, current, "count without a preceding rune"); err != nil {
+                return nil, err
+            }
+            continue
+        default:
+            r.text, size = nextUnit(p.s[start:], p.opts.Graphemes)
+            p.pos = start + size
+        }
+
+        if !d.PrefixCount {
+            var err error
+            if count, err = p.count(); err != nil {
+                if err := p.fail(err); err != nil {
+                    return nil, err
+                }
+                continue
+            }
+        }
+        if p.opts.MaxRepeat > 0 && count > p.opts.MaxRepeat {
+            return nil, ErrTooLarge
         }
+        // Zero counts, and groups made only of them, unpack to nothing.
+        if count == 0 || isGroup && len(r.group) == 0 {
+            continue
+        }
+        r.count = count
+        runs = append(runs, r)
     }
 
-    return string(result), nil
+    if open >= 0 {
+        return nil, p.syntaxError(open, '(', "unclosed group")
+    }
+    return runs, nil
 }
 
-func isAllDigits(s string) bool {
-    for _, r := range s {
-        if !unicode.IsDigit(r) {
-            return false
-        }
+// nextUnit returns the rune, or the grapheme cluster if graphemes is set, at
+// the start of s, and its length in bytes.
+func nextUnit(s string, graphemes bool) (string, int) {
+    if graphemes {
+        cluster, _, _, _ := uniseg.FirstGraphemeClusterInString(s, -1)
+        return cluster, len(cluster)
+    }
+    r, size := utf8.DecodeRuneInString(s)
+    if r == utf8.RuneError && size == 1 {
+        return string(r), size
     }
-    return s != ""
+    return s[:size], size
 }
 
-func extractNumber(runes []rune) (int, int) {
-    var digits []rune
-    
-    for i, r := range runes {
-        if unicode.IsDigit(r) {
-            digits = append(digits, r)
-        } else {
-            break
-        }
-        if i >= 10 {
+// count reads the repeat count at pos, 1 if there is none.
+func (p *parser) count() (int, error) {
+    count, n, err := extractNumber(p.s[p.pos:], p.base+p.pos, p.opts.Dialect)
+    p.pos += n
+    return count, err
+}
+
+// extractNumber reads the repeat count at the start of s, 1 if there is none,
+// and returns it with its length in bytes. offset is the position of s in
+// the input.
+func extractNumber(s string, offset int, d Dialect) (int, int, error) {
+    n := 0
+    for n < len(s) {
+        r, size := utf8.DecodeRuneInString(s[n:])
+        if !unicode.IsDigit(r) {
             break
         }
+        n += size
     }
-    
-    if len(digits) == 0 {
-        return 1, 0
+
+    if n == 0 {
+        return 1, 0, nil
     }
-    
-    num, err := strconv.Atoi(string(digits))
+
+    count, err := parseCount(s[:n], offset, d)
+    return count, n, err
+}
+
+// parseCount converts the digits of a repeat count found at offset.
+// Counts longer than the dialect allows are rejected with ErrTooLarge.
+func parseCount(digits string, offset int, d Dialect) (int, error) {
+    if utf8.RuneCountInString(digits) > d.maxDigits() {
+        return 0, ErrTooLarge
+    }
+    for i, r := range digits {
+        if r < '0' || r > '9' {
+            return 0, &SyntaxError{Offset: offset + i, Rune: r, Reason: "non-ASCII digit in count"}
+        }
+    }
+
+    count, err := strconv.Atoi(digits)
     if err != nil {
-        return 1, 0
+        return 0, ErrTooLarge
+    }
+    if count == 0 && !d.AllowZero {
+        return 0, &SyntaxError{Offset: offset, Rune: '0', Reason: "zero count"}
     }
-    
-    return num, len(digits)
-}
\ No newline at end of file
+
+    return count, nil
+}
@@ -1,7 +1,11 @@
 package unpack
 
 import (
+    "errors"
+    "reflect"
+    "strings"
     "testing"
+    "unicode/utf8"
 )
 
 func TestUnpack(t *testing.T) {
@@ -71,6 +75,24 @@ func TestUnpack(t *testing.T) {
             expected: "qweaaa",
             hasError: false,
         },
+        {
+            name:     "group",
+            input:    "(ab2)3c",
+            expected: "abbabbabbc",
+            hasError: false,
+        },
+        {
+            name:     "nested groups",
+            input:    "((ab)2c)2",
+            expected: "ababcababc",
+            hasError: false,
+        },
+        {
+            name:     "escaped parentheses",
+            input:    "\\(a2\\)",
+            expected: "(aa)",
+            hasError: false,
+        },
         
         // Ошибочные случаи
         {
@@ -107,7 +129,7 @@ func TestUnpack(t *testing.T) {
                 if err == nil {
                     t.Errorf("Unpack(%q) expected error, but got none", tt.input)
                 }
-                if err != ErrInvalidString {
+                if !errors.Is(err, ErrInvalidString) {
                     t.Errorf("Unpack(%q) expected ErrInvalidString, got %v", tt.input, err)
                 }
             } else {
@@ -122,9 +144,456 @@ func TestUnpack(t *testing.T) {
     }
 }
 
+func TestUnpackWithOptions(t *testing.T) {
+    tests := []struct {
+        name     string
+        input    string
+        opts     *Options
+        expected string
+        err      error
+    }{
+        {
+            name:     "within limits",
+            input:    "a4bc2d5e",
+            opts:     &Options{MaxOutputRunes: 13, MaxRepeat: 5},
+            expected: "aaaabccddddde",
+        },
+        {
+            name:  "output too large",
+            input: "a4bc2d5e",
+            opts:  &Options{MaxOutputRunes: 12},
+            err:   ErrTooLarge,
+        },
+        {
+            name:  "repeat too large",
+            input: "a4bc2d5e",
+            opts:  &Options{MaxRepeat: 4},
+            err:   ErrTooLarge,
+        },
+        {
+            name:  "bomb",
+            input: "a99999999999",
+            opts:  &Options{MaxOutputRunes: 1 << 20},
+            err:   ErrTooLarge,
+        },
+        {
+            name:  "bomb without limits",
+            input: "a9999999999b9999999999",
+            opts:  &Options{MaxRepeat: 1000},
+            err:   ErrTooLarge,
+        },
+        {
+            name:  "count too long",
+            input: "a123456789012",
+            opts:  nil,
+            err:   ErrTooLarge,
+        },
+        {
+            name:  "invalid before limits",
+            input: "1a",
+            opts:  &Options{MaxOutputRunes: 1},
+            err:   ErrInvalidString,
+        },
+        {
+            name:  "group bomb",
+            input: "((a9999999999)9999999999)9999999999",
+            opts:  nil,
+            err:   ErrTooLarge,
+        },
+        {
+            name:  "group bomb below MaxInt",
+            input: "(a99999999999)9999999",
+            opts:  nil,
+            err:   ErrTooLarge,
+        },
+        {
+            name:  "group too large",
+            input: "(ab)3",
+            opts:  &Options{MaxOutputRunes: 5},
+            err:   ErrTooLarge,
+        },
+        {
+            name:     "group within limits",
+            input:    "(ab)3",
+            opts:     &Options{MaxOutputRunes: 6, MaxRepeat: 3},
+            expected: "ababab",
+        },
+        {
+            name:     "combining mark without graphemes",
+            input:    "e\u03013",
+            opts:     nil,
+            expected: "e\u0301\u0301\u0301",
+        },
+        {
+            name:     "combining mark",
+            input:    "e\u03013",
+            opts:     &Options{Graphemes: true},
+            expected: "e\u0301e\u0301e\u0301",
+        },
+        {
+            name:     "emoji ZWJ sequence",
+            input:    "👨\u200d👩\u200d👧2!",
+            opts:     &Options{Graphemes: true},
+            expected: "👨\u200d👩\u200d👧👨\u200d👩\u200d👧!",
+        },
+        {
+            name:     "escaped cluster in group",
+            input:    "(\\5\u0301x)2",
+            opts:     &Options{Graphemes: true},
+            expected: "5\u0301x5\u0301x",
+        },
+        {
+            name:  "limit counts runes",
+            input: "e\u03012",
+            opts:  &Options{MaxOutputRunes: 3, Graphemes: true},
+            err:   ErrTooLarge,
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            result, err := UnpackWithOptions(tt.input, tt.opts)
+            if !errors.Is(err, tt.err) {
+                t.Fatalf("UnpackWithOptions(%q) error = %v, want %v", tt.input, err, tt.err)
+            }
+            if result != tt.expected {
+                t.Errorf("UnpackWithOptions(%q) = %q, want %q", tt.input, result, tt.expected)
+            }
+        })
+    }
+}
+
+func TestSyntaxError(t *testing.T) {
+    tests := []struct {
+        name     string
+        input    string
+        expected *SyntaxError
+    }{
+        {
+            name:     "digit at start",
+            input:    "12abc",
+            expected: &SyntaxError{Offset: 0, Rune: '1', Reason: "count without a preceding rune"},
+        },
+        {
+            name:     "escape at end",
+            input:    "é\\",
+            expected: &SyntaxError{Offset: 2, Rune: '\\', Reason: "unterminated escape"},
+        },
+        {
+            name:     "zero repeat",
+            input:    "ab00",
+            expected: &SyntaxError{Offset: 2, Rune: '0', Reason: "zero count"},
+        },
+        {
+            name:     "non-ASCII digit",
+            input:    "a1٣",
+            expected: &SyntaxError{Offset: 2, Rune: '٣', Reason: "non-ASCII digit in count"},
+        },
+        {
+            name:     "unmatched parenthesis",
+            input:    "ab)2",
+            expected: &SyntaxError{Offset: 2, Rune: ')', Reason: "unmatched ')'"},
+        },
+        {
+            name:     "unclosed group",
+            input:    "a(b(c)2",
+            expected: &SyntaxError{Offset: 1, Rune: '(', Reason: "unclosed group"},
+        },
+        {
+            name:     "empty group",
+            input:    "a()3",
+            expected: &SyntaxError{Offset: 1, Rune: '(', Reason: "empty group"},
+        },
+        {
+            name:     "error inside group",
+            input:    "(a0)",
+            expected: &SyntaxError{Offset: 2, Rune: '0', Reason: "zero count"},
+        },
+        {
+            name:     "nested too deeply",
+            input:    strings.Repeat("(", 101) + "a" + strings.Repeat(")", 101),
+            expected: &SyntaxError{Offset: 100, Rune: '(', Reason: "groups nested too deeply"},
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            _, err := Unpack(tt.input)
+
+            var syntaxErr *SyntaxError
+            if !errors.As(err, &syntaxErr) {
+                t.Fatalf("Unpack(%q) error = %v, want *SyntaxError", tt.input, err)
+            }
+            if !reflect.DeepEqual(syntaxErr, tt.expected) {
+                t.Errorf("Unpack(%q) error = %+v, want %+v", tt.input, syntaxErr, tt.expected)
+            }
+            if !errors.Is(err, ErrInvalidString) {
+                t.Errorf("errors.Is(%v, ErrInvalidString) = false", err)
+            }
+        })
+    }
+}
+
+func TestValidate(t *testing.T) {
+    tests := []struct {
+        name     string
+        input    string
+        expected []*SyntaxError
+    }{
+        {
+            name:     "valid",
+            input:    "a4bc2d5e\\45",
+            expected: nil,
+        },
+        {
+            name:  "several problems",
+            input: "12a0b3c\\",
+            expected: []*SyntaxError{
+                {Offset: 0, Rune: '1', Reason: "count without a preceding rune"},
+                {Offset: 3, Rune: '0', Reason: "zero count"},
+                {Offset: 7, Rune: '\\', Reason: "unterminated escape"},
+            },
+        },
+        {
+            name:     "long count is not a syntax error",
+            input:    "a123456789012",
+            expected: nil,
+        },
+        {
+            name:  "errors after a long count",
+            input: "a999999999999999 5 ()",
+            expected: []*SyntaxError{
+                {Offset: 19, Rune: '(', Reason: "empty group"},
+            },
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if errs := Validate(tt.input); !reflect.DeepEqual(errs, tt.expected) {
+                t.Errorf("Validate(%q) = %v, want %v", tt.input, errs, tt.expected)
+            }
+        })
+    }
+}
+
 // Бенчмарк для проверки производительности
 func BenchmarkUnpack(b *testing.B) {
     for i := 0; i < b.N; i++ {
         Unpack("a4bc2d5e\\4\\5qwe\\45")
     }
-}
\ No newline at end of file
+}
+
+func TestPack(t *testing.T) {
+    tests := []struct {
+        name     string
+        input    string
+        expected string
+    }{
+        {
+            name:     "runs",
+            input:    "aaaabccddddde",
+            expected: "a4bc2d5e",
+        },
+        {
+            name:     "no runs",
+            input:    "abcd",
+            expected: "abcd",
+        },
+        {
+            name:     "empty string",
+            input:    "",
+            expected: "",
+        },
+        {
+            name:     "digits",
+            input:    "qwe44444",
+            expected: "qwe\\45",
+        },
+        {
+            name:     "backslashes",
+            input:    "\\\\\\",
+            expected: "\\\\3",
+        },
+        {
+            name:     "non-ASCII digits",
+            input:    "x٣٣",
+            expected: "x\\٣2",
+        },
+        {
+            name:     "multibyte runs",
+            input:    "ééé日",
+            expected: "é3日",
+        },
+        {
+            name:     "parentheses",
+            input:    "f((x))",
+            expected: "f\\(2x\\)2",
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if result := Pack(tt.input); result != tt.expected {
+                t.Errorf("Pack(%q) = %q, want %q", tt.input, result, tt.expected)
+            }
+        })
+    }
+}
+
+func FuzzPackUnpack(f *testing.F) {
+    for _, seed := range []string{"", "aaaabccddddde", "qwe44444", "\\\\", "x٣٣", "ééé日", "a\n"} {
+        f.Add(seed)
+    }
+
+    f.Fuzz(func(t *testing.T, s string) {
+        if !utf8.ValidString(s) {
+            t.Skip()
+        }
+
+        packed := Pack(s)
+        result, err := Unpack(packed)
+        if err != nil {
+            t.Fatalf("Unpack(Pack(%q)) = error %v, packed %q", s, err, packed)
+        }
+        if result != s {
+            t.Fatalf("Unpack(Pack(%q)) = %q, packed %q", s, result, packed)
+        }
+    })
+}
+
+func TestPackGroups(t *testing.T) {
+    tests := []struct {
+        name     string
+        input    string
+        expected string
+    }{
+        {
+            name:     "repeated substring",
+            input:    "abbabbabbc",
+            expected: "(ab2)3c",
+        },
+        {
+            name:     "nested repeats",
+            input:    strings.Repeat("abababababc", 3),
+            expected: "((ab)5c)3",
+        },
+        {
+            name:     "runs only",
+            input:    "aaaabccddddde",
+            expected: "a4bc2d5e",
+        },
+        {
+            name:     "group not shorter",
+            input:    "abab",
+            expected: "abab",
+        },
+        {
+            name:     "escaped unit",
+            input:    "(1(1(1(1",
+            expected: "(\\(\\1)4",
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if result := PackGroups(tt.input); result != tt.expected {
+                t.Errorf("PackGroups(%q) = %q, want %q", tt.input, result, tt.expected)
+            }
+        })
+    }
+}
+
+func TestPackWithOptions(t *testing.T) {
+    tests := []struct {
+        name     string
+        input    string
+        expected string
+    }{
+        {
+            name:     "combining marks",
+            input:    "e\u0301e\u0301e\u0301e",
+            expected: "e\u03013e",
+        },
+        {
+            name:     "emoji ZWJ sequence",
+            input:    "👨\u200d👩\u200d👧👨\u200d👩\u200d👧",
+            expected: "👨\u200d👩\u200d👧2",
+        },
+        {
+            name:     "escaped cluster",
+            input:    "5\u03015\u0301(",
+            expected: "\\5\u03012\\(",
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if result := PackWithOptions(tt.input, &Options{Graphemes: true}); result != tt.expected {
+                t.Errorf("PackWithOptions(%q) = %q, want %q", tt.input, result, tt.expected)
+            }
+        })
+    }
+}
+
+func FuzzPackUnpackGraphemes(f *testing.F) {
+    for _, seed := range []string{"", "e\u0301e\u0301", "👨\u200d👩\u200d👧2", "5\u0301", "🇦🇧🇦🇧🇦", "\u0600(", "\r\n\r\n"} {
+        f.Add(seed)
+    }
+
+    f.Fuzz(func(t *testing.T, s string) {
+        if !utf8.ValidString(s) {
+            t.Skip()
+        }
+
+        opts := &Options{Graphemes: true}
+        packed := PackWithOptions(s, opts)
+        result, err := UnpackWithOptions(packed, opts)
+        if err != nil {
+            t.Fatalf("UnpackWithOptions(PackWithOptions(%q)) = error %v, packed %q", s, err, packed)
+        }
+        if result != s {
+            t.Fatalf("UnpackWithOptions(PackWithOptions(%q)) = %q, packed %q", s, result, packed)
+        }
+    })
+}
+
+func FuzzPackGroupsUnpack(f *testing.F) {
+    for _, seed := range []string{"", "abbabbabbc", "ababcababcababc", "(1(1(1(1", "x٣٣x٣٣x٣٣"} {
+        f.Add(seed)
+    }
+
+    f.Fuzz(func(t *testing.T, s string) {
+        if !utf8.ValidString(s) {
+            t.Skip()
+        }
+
+        packed := PackGroups(s)
+        result, err := Unpack(packed)
+        if err != nil {
+            t.Fatalf("Unpack(PackGroups(%q)) = error %v, packed %q", s, err, packed)
+        }
+        if result != s {
+            t.Fatalf("Unpack(PackGroups(%q)) = %q, packed %q", s, result, packed)
+        }
+    })
+}
+
+func FuzzUnpackPack(f *testing.F) {
+    for _, seed := range []string{"", "a4bc2d5e", "qwe\\45", "\\\\3", "é3日", "(ab2)3c", "a(b", "a\xff3"} {
+        f.Add(seed)
+    }
+
+    f.Fuzz(func(t *testing.T, s string) {
+        // Arbitrary input must never panic; whatever unpacks must survive
+        // a round trip through Pack.
+        unpacked, err := UnpackWithOptions(s, &Options{MaxOutputRunes: 1 << 16})
+        if err != nil {
+            return
+        }
+        packed := Pack(unpacked)
+        if result, err := Unpack(packed); err != nil || result != unpacked {
+            t.Fatalf("Unpack(Pack(%q)) = %q, %v, packed %q", unpacked, result, err, packed)
+        }
+    })
+}